@@ -2,6 +2,10 @@ package conf
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
 
 	"github.com/conductorone/baton-workato/pkg/connector/workato"
 
@@ -23,26 +27,165 @@ var (
 		field.WithDefaultValue("us"),
 	)
 
+	WorkatoBaseUrl = field.StringField(
+		"workato-base-url",
+		field.WithDescription("Base url of the Workato API, overrides workato-data-center. Use it for Embedded vanity domains, proxies or a local stand-in"),
+	)
+
+	WorkatoCABundle = field.StringField(
+		"workato-ca-bundle",
+		field.WithDescription("Path of a PEM file with extra CA certificates to trust when calling the Workato API"),
+	)
+
+	WorkatoProxyUrl = field.StringField(
+		"workato-proxy-url",
+		field.WithDescription("Proxy used to call the Workato API, by default HTTPS_PROXY and HTTP_PROXY are used"),
+	)
+
 	WorkatoEnv = field.StringField(
 		"workato-env",
 		field.WithDescription("Your workato environment (dev, test, prod) default is 'dev'"),
 		field.WithDefaultValue("dev"),
 	)
 
+	WorkatoMultiEnv = field.BoolField(
+		"workato-multi-env",
+		field.WithDescription("Sync every environment with an API key in one run, workato-api-key belongs to workato-env"),
+	)
+
+	WorkatoDevApiKey = field.StringField(
+		"workato-dev-api-key",
+		field.WithDescription("API key of the dev environment, used with workato-multi-env"),
+	)
+
+	WorkatoTestApiKey = field.StringField(
+		"workato-test-api-key",
+		field.WithDescription("API key of the test environment, used with workato-multi-env"),
+	)
+
+	WorkatoProdApiKey = field.StringField(
+		"workato-prod-api-key",
+		field.WithDescription("API key of the prod environment, used with workato-multi-env"),
+	)
+
+	WorkatoRevokeFallbackRole = field.StringField(
+		"workato-revoke-fallback-role",
+		field.WithDescription("Role given to a collaborator when its last role is revoked, Workato needs at least one role. Empty refuses to revoke the last role"),
+	)
+
+	WorkatoElevatedRoles = field.StringSliceField(
+		"workato-elevated-roles",
+		field.WithDescription("Roles granted temporarily, the role they replace in the environment is restored when they are revoked or expire, or they are removed when the environment had none. Needs workato-state-file"),
+	)
+
+	WorkatoElevationDuration = field.StringField(
+		"workato-elevation-duration",
+		field.WithDescription("How long an elevated role is kept, e.g. 8h. Expired roles are restored at the start of the next sync, empty keeps them until revoked"),
+	)
+
+	WorkatoDryRun = field.BoolField(
+		"workato-dry-run",
+		field.WithDescription("Log the changes of grants, revokes, invitations, creations and deletions and fail them instead of applying them, only reads are sent to Workato"),
+	)
+
+	WorkatoApiPlatform = field.BoolField(
+		"workato-api-platform",
+		field.WithDescription("Sync API Platform collections, clients and access profiles, the API key needs the API Platform scopes"),
+	)
+
+	WorkatoOnPrem = field.BoolField(
+		"workato-on-prem",
+		field.WithDescription("Sync on-prem groups and agents, the API key needs the on-prem scopes"),
+	)
+
+	WorkatoRecipes = field.BoolField(
+		"workato-recipes",
+		field.WithDescription("Sync recipes under their folders, the API key needs the recipes scopes"),
+	)
+
+	WorkatoConnections = field.BoolField(
+		"workato-connections",
+		field.WithDescription("Sync connections under their folders, the API key needs the connections scopes"),
+	)
+
+	WorkatoTables = field.BoolField(
+		"workato-tables",
+		field.WithDescription("Sync lookup tables and data tables under their folders, the API key needs the scopes of both"),
+	)
+
+	WorkatoRequestsPerMinute = field.IntField(
+		"workato-requests-per-minute",
+		field.WithDescription("Maximum number of requests sent to the Workato API per minute, 0 disables the limit"),
+		field.WithDefaultValue(0),
+	)
+
+	WorkatoPrivilegesConcurrency = field.IntField(
+		"workato-privileges-concurrency",
+		field.WithDescription("Number of collaborator privileges fetched at the same time"),
+		field.WithDefaultValue(4),
+	)
+
+	WorkatoStateFile = field.StringField(
+		"workato-state-file",
+		field.WithDescription("Path of a file where the connector keeps state between runs"),
+	)
+
+	WorkatoIncrementalSync = field.BoolField(
+		"workato-incremental-sync",
+		field.WithDescription("Only fetch privileges of collaborators that changed since the previous sync, needs workato-state-file"),
+	)
+
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
 	ConfigurationFields = []field.SchemaField{
 		ApiKeyField,
 		WorkatoDataCenterFiekd,
+		WorkatoBaseUrl,
+		WorkatoCABundle,
+		WorkatoProxyUrl,
 		WorkatoEnv,
+		WorkatoMultiEnv,
+		WorkatoDevApiKey,
+		WorkatoTestApiKey,
+		WorkatoProdApiKey,
+		WorkatoRevokeFallbackRole,
+		WorkatoElevatedRoles,
+		WorkatoElevationDuration,
+		WorkatoDryRun,
+		WorkatoApiPlatform,
+		WorkatoOnPrem,
+		WorkatoRecipes,
+		WorkatoConnections,
+		WorkatoTables,
+		WorkatoRequestsPerMinute,
+		WorkatoPrivilegesConcurrency,
+		WorkatoStateFile,
+		WorkatoIncrementalSync,
 	}
 
 	// FieldRelationships defines relationships between the fields listed in
 	// ConfigurationFields that can be automatically validated. For example, a
 	// username and password can be required together, or an access token can be
 	// marked as mutually exclusive from the username password pair.
-	FieldRelationships = []field.SchemaFieldRelationship{}
+	FieldRelationships = []field.SchemaFieldRelationship{
+		field.FieldsDependentOn(
+			[]field.SchemaField{WorkatoIncrementalSync},
+			[]field.SchemaField{WorkatoStateFile},
+		),
+		field.FieldsDependentOn(
+			[]field.SchemaField{WorkatoDevApiKey, WorkatoTestApiKey, WorkatoProdApiKey},
+			[]field.SchemaField{WorkatoMultiEnv},
+		),
+		field.FieldsDependentOn(
+			[]field.SchemaField{WorkatoElevatedRoles},
+			[]field.SchemaField{WorkatoStateFile},
+		),
+		field.FieldsDependentOn(
+			[]field.SchemaField{WorkatoElevationDuration},
+			[]field.SchemaField{WorkatoElevatedRoles},
+		),
+	}
 )
 
 // ValidateConfig is run after the configuration is loaded, and should return an
@@ -50,14 +193,114 @@ var (
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
 func ValidateConfig(v *viper.Viper) error {
-	if _, ok := client.WorkatoDataCenters[v.GetString(WorkatoDataCenterFiekd.FieldName)]; !ok {
+	// The data center is ignored when a base url is set.
+	if baseUrl := v.GetString(WorkatoBaseUrl.FieldName); baseUrl != "" {
+		if err := validateUrl(baseUrl, "http", "https"); err != nil {
+			return fmt.Errorf("invalid workato base url: %w", err)
+		}
+	} else if _, ok := client.WorkatoDataCenters[v.GetString(WorkatoDataCenterFiekd.FieldName)]; !ok {
 		return errors.New("invalid workato data center")
 	}
 
-	_, err := workato.EnvFromString(v.GetString(WorkatoEnv.FieldName))
+	if proxyUrl := v.GetString(WorkatoProxyUrl.FieldName); proxyUrl != "" {
+		if err := validateUrl(proxyUrl, "http", "https", "socks5"); err != nil {
+			return fmt.Errorf("invalid workato proxy url: %w", err)
+		}
+	}
+
+	env, err := workato.EnvFromString(v.GetString(WorkatoEnv.FieldName))
+	if err != nil {
+		return err
+	}
+
+	if v.GetBool(WorkatoMultiEnv.FieldName) {
+		keys := EnvApiKeys(v)
+		if len(keys) == 0 {
+			return errors.New("workato multi env needs the API key of another environment")
+		}
+
+		if _, ok := keys[env]; ok {
+			return fmt.Errorf("workato-api-key is the key of the %s environment, leave workato-%s-api-key empty", env, env)
+		}
+	}
+
+	if _, err := ElevationDuration(v); err != nil {
+		return err
+	}
+
+	if v.GetInt(WorkatoRequestsPerMinute.FieldName) < 0 {
+		return errors.New("workato requests per minute must be zero or positive")
+	}
+
+	if v.GetInt(WorkatoPrivilegesConcurrency.FieldName) < 1 {
+		return errors.New("workato privileges concurrency must be at least 1")
+	}
+
+	return nil
+}
+
+// BaseUrl returns the url of the Workato API, workato-base-url takes precedence over workato-data-center.
+func BaseUrl(v *viper.Viper) string {
+	if baseUrl := v.GetString(WorkatoBaseUrl.FieldName); baseUrl != "" {
+		return baseUrl
+	}
+
+	return client.WorkatoDataCenters[v.GetString(WorkatoDataCenterFiekd.FieldName)]
+}
+
+func validateUrl(rawUrl string, schemes ...string) error {
+	u, err := url.Parse(rawUrl)
 	if err != nil {
 		return err
 	}
 
+	if !slices.Contains(schemes, u.Scheme) {
+		return fmt.Errorf("scheme must be one of %v", schemes)
+	}
+
+	if u.Host == "" {
+		return errors.New("host is missing")
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("query and fragment are not allowed")
+	}
+
 	return nil
 }
+
+// ElevationDuration returns how long an elevated role is kept, zero keeps it until it's revoked.
+func ElevationDuration(v *viper.Viper) (time.Duration, error) {
+	value := v.GetString(WorkatoElevationDuration.FieldName)
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid workato elevation duration: %w", err)
+	}
+
+	if duration <= 0 {
+		return 0, errors.New("workato elevation duration must be positive")
+	}
+
+	return duration, nil
+}
+
+// EnvApiKeys returns the API keys of the environments set for multi environment mode.
+func EnvApiKeys(v *viper.Viper) map[workato.Environment]string {
+	keys := make(map[workato.Environment]string)
+
+	for env, f := range map[workato.Environment]field.SchemaField{
+		workato.Development: WorkatoDevApiKey,
+		workato.Test:        WorkatoTestApiKey,
+		workato.Production:  WorkatoProdApiKey,
+	} {
+		if key := v.GetString(f.FieldName); key != "" {
+			keys[env] = key
+		}
+	}
+
+	return keys
+}
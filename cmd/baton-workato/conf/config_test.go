@@ -13,8 +13,122 @@ func TestConfigs(t *testing.T) {
 		FieldRelationships...,
 	)
 
+	valid := func(overrides map[string]string) map[string]string {
+		configs := map[string]string{
+			"workato-api-key":                "key",
+			"workato-data-center":            "us",
+			"workato-env":                    "dev",
+			"workato-privileges-concurrency": "4",
+		}
+		for key, value := range overrides {
+			configs[key] = value
+		}
+		return configs
+	}
+
 	testCases := []test.TestCase{
-		// Add test cases here.
+		{
+			Configs: valid(nil),
+			IsValid: true,
+			Message: "defaults",
+		},
+		{
+			Configs: valid(map[string]string{"workato-data-center": "mars"}),
+			IsValid: false,
+			Message: "unknown data center",
+		},
+		{
+			Configs: valid(map[string]string{
+				"workato-data-center": "mars",
+				"workato-base-url":    "https://workato.example.com",
+			}),
+			IsValid: true,
+			Message: "base url overrides the data center",
+		},
+		{
+			Configs: valid(map[string]string{"workato-base-url": "http://localhost:8080"}),
+			IsValid: true,
+			Message: "local stand-in",
+		},
+		{
+			Configs: valid(map[string]string{"workato-base-url": "ftp://workato.example.com"}),
+			IsValid: false,
+			Message: "base url scheme",
+		},
+		{
+			Configs: valid(map[string]string{"workato-base-url": "workato.example.com"}),
+			IsValid: false,
+			Message: "base url without scheme",
+		},
+		{
+			Configs: valid(map[string]string{"workato-base-url": "https://workato.example.com?a=b"}),
+			IsValid: false,
+			Message: "base url with query",
+		},
+		{
+			Configs: valid(map[string]string{"workato-proxy-url": "http://proxy.example.com:3128"}),
+			IsValid: true,
+			Message: "proxy url",
+		},
+		{
+			Configs: valid(map[string]string{"workato-proxy-url": "proxy.example.com:3128"}),
+			IsValid: false,
+			Message: "proxy url without scheme",
+		},
+		{
+			Configs: valid(map[string]string{
+				"workato-multi-env":    "true",
+				"workato-prod-api-key": "prod-key",
+			}),
+			IsValid: true,
+			Message: "multi env",
+		},
+		{
+			Configs: valid(map[string]string{"workato-multi-env": "true"}),
+			IsValid: false,
+			Message: "multi env without keys",
+		},
+		{
+			Configs: valid(map[string]string{"workato-prod-api-key": "prod-key"}),
+			IsValid: false,
+			Message: "environment key without multi env",
+		},
+		{
+			Configs: valid(map[string]string{
+				"workato-multi-env":   "true",
+				"workato-dev-api-key": "dev-key",
+			}),
+			IsValid: false,
+			Message: "key of the main environment",
+		},
+		{
+			Configs: valid(map[string]string{
+				"workato-state-file":         "state.json",
+				"workato-elevated-roles":     "Admin",
+				"workato-elevation-duration": "8h",
+			}),
+			IsValid: true,
+			Message: "elevated roles",
+		},
+		{
+			Configs: valid(map[string]string{"workato-elevated-roles": "Admin"}),
+			IsValid: false,
+			Message: "elevated roles without state file",
+		},
+		{
+			Configs: valid(map[string]string{
+				"workato-state-file":         "state.json",
+				"workato-elevated-roles":     "Admin",
+				"workato-elevation-duration": "soon",
+			}),
+			IsValid: false,
+			Message: "elevation duration",
+		},
+		{
+			Configs: valid(map[string]string{"workato-privileges-concurrency": "0"}),
+			IsValid: false,
+			Message: "privileges concurrency",
+		},
 	}
 
 	test.ExerciseTestCases(t, configurationSchema, ValidateConfig, testCases)
@@ -10,6 +10,7 @@ import (
 	"github.com/conductorone/baton-workato/cmd/baton-workato/conf"
 
 	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/store"
 
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
@@ -31,7 +32,8 @@ func main() {
 		"baton-workato",
 		getConnector,
 		field.Configuration{
-			Fields: conf.ConfigurationFields,
+			Fields:      conf.ConfigurationFields,
+			Constraints: conf.FieldRelationships,
 		},
 	)
 	if err != nil {
@@ -55,19 +57,63 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 	}
 
 	key := v.GetString(conf.ApiKeyField.FieldName)
-	dataCenterUrl := client.WorkatoDataCenters[v.GetString(conf.WorkatoDataCenterFiekd.FieldName)]
+	baseUrl := conf.BaseUrl(v)
 
 	env, err := workato.EnvFromString(v.GetString(conf.WorkatoEnv.FieldName))
 	if err != nil {
 		return nil, err
 	}
 
-	workatoClient, err := client.NewWorkatoClient(ctx, key, dataCenterUrl)
+	clientOpts := []client.Option{
+		client.WithRequestsPerMinute(v.GetInt(conf.WorkatoRequestsPerMinute.FieldName)),
+		client.WithCABundle(v.GetString(conf.WorkatoCABundle.FieldName)),
+		client.WithProxy(v.GetString(conf.WorkatoProxyUrl.FieldName)),
+		client.WithDryRun(v.GetBool(conf.WorkatoDryRun.FieldName)),
+	}
+
+	workatoClient, err := client.NewWorkatoClient(ctx, key, baseUrl, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	cb, err := connector.New(ctx, workatoClient, env)
+	opts := []connector.Option{
+		connector.WithPrivilegesConcurrency(v.GetInt(conf.WorkatoPrivilegesConcurrency.FieldName)),
+		connector.WithIncrementalSync(v.GetBool(conf.WorkatoIncrementalSync.FieldName)),
+		connector.WithRevokeFallbackRole(v.GetString(conf.WorkatoRevokeFallbackRole.FieldName)),
+		connector.WithDryRun(v.GetBool(conf.WorkatoDryRun.FieldName)),
+		connector.WithApiPlatform(v.GetBool(conf.WorkatoApiPlatform.FieldName)),
+		connector.WithOnPrem(v.GetBool(conf.WorkatoOnPrem.FieldName)),
+		connector.WithRecipes(v.GetBool(conf.WorkatoRecipes.FieldName)),
+		connector.WithConnections(v.GetBool(conf.WorkatoConnections.FieldName)),
+		connector.WithTables(v.GetBool(conf.WorkatoTables.FieldName)),
+	}
+
+	if elevatedRoles := v.GetStringSlice(conf.WorkatoElevatedRoles.FieldName); len(elevatedRoles) > 0 {
+		duration, err := conf.ElevationDuration(v)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, connector.WithElevation(elevatedRoles, duration))
+	}
+
+	if v.GetBool(conf.WorkatoMultiEnv.FieldName) {
+		envClients := make(map[workato.Environment]*client.WorkatoClient)
+		for otherEnv, otherKey := range conf.EnvApiKeys(v) {
+			envClients[otherEnv], err = client.NewWorkatoClient(ctx, otherKey, baseUrl, clientOpts...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		opts = append(opts, connector.WithEnvironmentClients(envClients))
+	}
+
+	if stateFile := v.GetString(conf.WorkatoStateFile.FieldName); stateFile != "" {
+		opts = append(opts, connector.WithStore(store.NewFileStore(stateFile)))
+	}
+
+	cb, err := connector.New(ctx, workatoClient, env, opts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
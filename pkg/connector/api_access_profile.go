@@ -0,0 +1,272 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// apiAccessProfileBuilder syncs the API Platform access profiles.
+// A profile belongs to one client and only works while it's active, so the client holds the profile while it's active.
+type apiAccessProfileBuilder struct {
+	envs     *environments
+	profiles *accessProfileCache
+	// dryRun logs the access profile changes of grants and revokes instead of applying them.
+	dryRun bool
+}
+
+func (o *apiAccessProfileBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiAccessProfileResourceType
+}
+
+// List returns the access profiles, in multi environment mode they are listed under their environment.
+func (o *apiAccessProfileBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	env, ok, err := o.envs.parentEnv(parentResourceID)
+	if err != nil || !ok {
+		return nil, "", nil, err
+	}
+
+	if pToken.Token == "" {
+		o.profiles.beginSync()
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	profiles, nextToken, annos, err := envClient.GetApiAccessProfiles(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(profiles))
+
+	for _, profile := range profiles {
+		us, err := apiAccessProfileResource(&profile, o.envs.resourceId(env, profile.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+func (o *apiAccessProfileBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(apiClientResourceType),
+		entitlement.WithDescription(fmt.Sprintf("%s holds %s", apiClientResourceType.DisplayName, resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s have %s", apiClientResourceType.DisplayName, resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(resource, assignedEntitlement, assigmentOptions...),
+	}, "", nil, nil
+}
+
+// Grants returns the client of the access profile while the profile is active.
+func (o *apiAccessProfileBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, profileId, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	profile, err := o.profiles.getProfile(ctx, env, profileId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if profile == nil || !profile.Active {
+		return nil, "", nil, nil
+	}
+
+	clientId, err := rs.NewResourceID(apiClientResourceType, o.envs.resourceId(env, profile.ApiClientId))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Grant{grant.NewGrant(resource, assignedEntitlement, clientId)}, "", nil, nil
+}
+
+// Grant moves the access profile to the client and enables it.
+// A profile active for another client is refused, moving it would silently revoke the access of that client.
+func (o *apiAccessProfileBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != apiClientResourceType.Id {
+		return nil, nil, fmt.Errorf("grant not implemented for %s", principal.Id.ResourceType)
+	}
+
+	env, profileId, clientId, err := o.envs.parseSameEnv(entitlement.Resource.Id, principal.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err := findAccessProfile(ctx, envClient, profileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grants := []*v2.Grant{grant.NewGrant(entitlement.Resource, assignedEntitlement, principal.Id)}
+
+	if profile.ApiClientId == clientId && profile.Active {
+		return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	if profile.Active {
+		return nil, nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: access profile %s is active for API client %d, revoke it before granting it to another client",
+			profile.Name,
+			profile.ApiClientId,
+		)
+	}
+
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info(
+			"baton-workato: dry run, access profile was not assigned",
+			zap.String("access_profile", profile.Name),
+			zap.Int("api_client_id", clientId),
+			zap.Bool("enable", !profile.Active),
+		)
+		return nil, nil, dryRunError("access profile %s was not assigned", profile.Name)
+	}
+
+	annos := annotations.Annotations{}
+
+	if profile.ApiClientId != clientId {
+		request := profile.Request()
+		request.ApiClientId = clientId
+
+		updateAnnos, err := envClient.UpdateApiAccessProfile(ctx, profile.Id, request)
+		annos.Merge(updateAnnos...)
+		if err != nil {
+			return nil, annos, err
+		}
+	}
+
+	if !profile.Active {
+		enableAnnos, err := envClient.EnableApiAccessProfile(ctx, profile.Id)
+		annos.Merge(enableAnnos...)
+		if err != nil {
+			return nil, annos, err
+		}
+	}
+
+	return grants, annos, nil
+}
+
+// Revoke disables the access profile, Workato keeps it with its client and collections so it can be granted back.
+func (o *apiAccessProfileBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if grant.Principal.Id.ResourceType != apiClientResourceType.Id {
+		return nil, fmt.Errorf("revoke not implemented for %s", grant.Principal.Id.ResourceType)
+	}
+
+	env, profileId, clientId, err := o.envs.parseSameEnv(grant.Entitlement.Resource.Id, grant.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := findAccessProfile(ctx, envClient, profileId)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+
+		return nil, err
+	}
+
+	if profile.ApiClientId != clientId || !profile.Active {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info("baton-workato: dry run, access profile was not disabled", zap.String("access_profile", profile.Name))
+		return nil, dryRunError("access profile %s was not disabled", profile.Name)
+	}
+
+	return envClient.DisableApiAccessProfile(ctx, profile.Id)
+}
+
+// findAccessProfile reads the current access profile, skipping the cached responses of the sync.
+func findAccessProfile(ctx context.Context, envClient *client.WorkatoClient, id int) (*client.ApiAccessProfile, error) {
+	envClient.InvalidateCache(ctx)
+
+	profiles, err := envClient.GetAllApiAccessProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Id == id {
+			return &profile, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "baton-workato: API access profile %d not found", id)
+}
+
+func newApiAccessProfileBuilder(envs *environments, profiles *accessProfileCache, dryRun bool) *apiAccessProfileBuilder {
+	return &apiAccessProfileBuilder{
+		envs:     envs,
+		profiles: profiles,
+		dryRun:   dryRun,
+	}
+}
+
+func apiAccessProfileResource(profile *client.ApiAccessProfile, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	collectionIds := make([]interface{}, 0, len(profile.ApiCollectionIds))
+	for _, id := range profile.ApiCollectionIds {
+		collectionIds = append(collectionIds, id)
+	}
+
+	appProfile := map[string]interface{}{
+		"id":                 profile.Id,
+		"name":               profile.Name,
+		"api_client_id":      profile.ApiClientId,
+		"api_collection_ids": collectionIds,
+		"active":             profile.Active,
+		"auth_type":          profile.AuthType,
+		"created_at":         profile.CreatedAt.String(),
+		"updated_at":         profile.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(appProfile),
+	}
+
+	ret, err := rs.NewAppResource(
+		profile.Name,
+		apiAccessProfileResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
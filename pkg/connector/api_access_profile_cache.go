@@ -0,0 +1,84 @@
+package connector
+
+import (
+	"context"
+	"slices"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+// accessProfileCache keeps the API access profiles of every environment, collections and profiles read them for their grants.
+type accessProfileCache struct {
+	envs     *environments
+	profiles map[workato.Environment][]client.ApiAccessProfile
+	lazy     lazyCache
+}
+
+func newAccessProfileCache(envs *environments) *accessProfileCache {
+	cache := &accessProfileCache{
+		envs:     envs,
+		profiles: make(map[workato.Environment][]client.ApiAccessProfile),
+	}
+
+	cache.lazy.build = cache.buildCache
+	cache.lazy.invalidate = envs.invalidateCache
+
+	return cache
+}
+
+func (p *accessProfileCache) beginSync() {
+	p.lazy.beginSync()
+}
+
+func (p *accessProfileCache) buildCache(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+
+	l.Info("Building cache for API access profiles")
+
+	p.profiles = make(map[workato.Environment][]client.ApiAccessProfile)
+
+	for _, env := range p.envs.list() {
+		envClient, err := p.envs.client(env)
+		if err != nil {
+			return err
+		}
+
+		profiles, err := envClient.GetAllApiAccessProfiles(ctx)
+		if err != nil {
+			return err
+		}
+
+		p.profiles[env] = profiles
+	}
+
+	l.Info("Cache built for API access profiles")
+
+	return nil
+}
+
+// getProfilesByCollection returns the access profiles of the environment reaching the collection.
+func (p *accessProfileCache) getProfilesByCollection(ctx context.Context, env workato.Environment, collectionId int) ([]client.ApiAccessProfile, error) {
+	rv := make([]client.ApiAccessProfile, 0)
+	err := p.lazy.read(ctx, func() {
+		for _, profile := range p.profiles[env] {
+			if slices.Contains(profile.ApiCollectionIds, collectionId) {
+				rv = append(rv, profile)
+			}
+		}
+	})
+	return rv, err
+}
+
+func (p *accessProfileCache) getProfile(ctx context.Context, env workato.Environment, id int) (*client.ApiAccessProfile, error) {
+	var rv *client.ApiAccessProfile
+	err := p.lazy.read(ctx, func() {
+		for _, profile := range p.profiles[env] {
+			if profile.Id == id {
+				rv = &profile
+			}
+		}
+	})
+	return rv, err
+}
@@ -0,0 +1,93 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// apiClientBuilder syncs the API Platform clients, they are the principals access profiles are granted to.
+type apiClientBuilder struct {
+	envs *environments
+}
+
+func (o *apiClientBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiClientResourceType
+}
+
+// List returns the API clients, in multi environment mode they are listed under their environment.
+func (o *apiClientBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	env, ok, err := o.envs.parentEnv(parentResourceID)
+	if err != nil || !ok {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	apiClients, nextToken, annos, err := envClient.GetApiClients(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(apiClients))
+
+	for _, apiClient := range apiClients {
+		us, err := apiClientResource(&apiClient, o.envs.resourceId(env, apiClient.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+// Entitlements always returns an empty slice, clients get their access through access profiles.
+func (o *apiClientBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice, the access profiles list the client holding them.
+func (o *apiClientBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newApiClientBuilder(envs *environments) *apiClientBuilder {
+	return &apiClientBuilder{
+		envs: envs,
+	}
+}
+
+func apiClientResource(apiClient *client.ApiClient, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":         apiClient.Id,
+		"name":       apiClient.Name,
+		"created_at": apiClient.CreatedAt.String(),
+		"updated_at": apiClient.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		apiClient.Name,
+		apiClientResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
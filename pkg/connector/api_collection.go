@@ -0,0 +1,245 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const apiCollectionAccessEntitlement = "access"
+
+type apiCollectionBuilder struct {
+	envs     *environments
+	profiles *accessProfileCache
+	// dryRun logs the access profile changes of grants and revokes instead of applying them.
+	dryRun bool
+}
+
+func (o *apiCollectionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return apiCollectionResourceType
+}
+
+// List returns the API collections, in multi environment mode they are listed under their environment.
+func (o *apiCollectionBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	env, ok, err := o.envs.parentEnv(parentResourceID)
+	if err != nil || !ok {
+		return nil, "", nil, err
+	}
+
+	if pToken.Token == "" {
+		o.profiles.beginSync()
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	collections, nextToken, annos, err := envClient.GetApiCollections(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(collections))
+
+	for _, collection := range collections {
+		us, err := apiCollectionResource(&collection, o.envs.resourceId(env, collection.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+func (o *apiCollectionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(apiAccessProfileResourceType),
+		entitlement.WithDescription(fmt.Sprintf("%s can call the endpoints of %s", apiAccessProfileResourceType.DisplayName, resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s access %s", apiAccessProfileResourceType.DisplayName, resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(resource, apiCollectionAccessEntitlement, assigmentOptions...),
+	}, "", nil, nil
+}
+
+// Grants returns the access profiles reaching the collection.
+func (o *apiCollectionBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, collectionId, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	profiles, err := o.profiles.getProfilesByCollection(ctx, env, collectionId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Grant, 0, len(profiles))
+
+	for _, profile := range profiles {
+		profileId, err := rs.NewResourceID(apiAccessProfileResourceType, o.envs.resourceId(env, profile.Id))
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, grant.NewGrant(resource, apiCollectionAccessEntitlement, profileId))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grant adds the collection to the collections of the access profile.
+func (o *apiCollectionBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != apiAccessProfileResourceType.Id {
+		return nil, nil, fmt.Errorf("grant not implemented for %s", principal.Id.ResourceType)
+	}
+
+	env, collectionId, profileId, err := o.envs.parseSameEnv(entitlement.Resource.Id, principal.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err := findAccessProfile(ctx, envClient, profileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grants := []*v2.Grant{grant.NewGrant(entitlement.Resource, apiCollectionAccessEntitlement, principal.Id)}
+
+	if slices.Contains(profile.ApiCollectionIds, collectionId) {
+		return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	request := profile.Request()
+	request.ApiCollectionIds = append(slices.Clone(profile.ApiCollectionIds), collectionId)
+
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info(
+			"baton-workato: dry run, access profile collections were not updated",
+			zap.String("access_profile", profile.Name),
+			zap.Ints("api_collection_ids", request.ApiCollectionIds),
+		)
+		return nil, nil, dryRunError("collections of access profile %s were not updated", profile.Name)
+	}
+
+	annos, err := envClient.UpdateApiAccessProfile(ctx, profile.Id, request)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return grants, annos, nil
+}
+
+// Revoke removes the collection from the collections of the access profile.
+// Workato needs at least one collection per profile, the last one is revoked by disabling or deleting the profile.
+func (o *apiCollectionBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if grant.Principal.Id.ResourceType != apiAccessProfileResourceType.Id {
+		return nil, fmt.Errorf("revoke not implemented for %s", grant.Principal.Id.ResourceType)
+	}
+
+	env, collectionId, profileId, err := o.envs.parseSameEnv(grant.Entitlement.Resource.Id, grant.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := findAccessProfile(ctx, envClient, profileId)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+
+		return nil, err
+	}
+
+	index := slices.Index(profile.ApiCollectionIds, collectionId)
+	if index < 0 {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	if len(profile.ApiCollectionIds) == 1 {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: %s is the last collection of access profile %s, revoke the profile from its client instead",
+			grant.Entitlement.Resource.Id.Resource,
+			profile.Name,
+		)
+	}
+
+	request := profile.Request()
+	request.ApiCollectionIds = slices.Delete(slices.Clone(profile.ApiCollectionIds), index, index+1)
+
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info(
+			"baton-workato: dry run, access profile collections were not updated",
+			zap.String("access_profile", profile.Name),
+			zap.Ints("api_collection_ids", request.ApiCollectionIds),
+		)
+		return nil, dryRunError("collections of access profile %s were not updated", profile.Name)
+	}
+
+	return envClient.UpdateApiAccessProfile(ctx, profile.Id, request)
+}
+
+func newApiCollectionBuilder(envs *environments, profiles *accessProfileCache, dryRun bool) *apiCollectionBuilder {
+	return &apiCollectionBuilder{
+		envs:     envs,
+		profiles: profiles,
+		dryRun:   dryRun,
+	}
+}
+
+func apiCollectionResource(collection *client.ApiCollection, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":           collection.Id,
+		"name":         collection.Name,
+		"version":      collection.Version,
+		"url":          collection.Url,
+		"api_spec_url": collection.ApiSpecUrl,
+		"created_at":   collection.CreatedAt.String(),
+		"updated_at":   collection.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		collection.Name,
+		apiCollectionResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// GetApiCollections returns one page of API Platform collections, pages start at 1.
+// https://docs.workato.com/workato-api/api-platform.html#list-api-collections
+func (c *WorkatoClient) GetApiCollections(ctx context.Context, pToken string) ([]ApiCollection, string, annotations.Annotations, error) {
+	var response []ApiCollection
+
+	uri, page, err := c.cappedPage(GetApiCollectionsPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
+
+// GetApiClients returns one page of API Platform clients, pages start at 1.
+func (c *WorkatoClient) GetApiClients(ctx context.Context, pToken string) ([]ApiClient, string, annotations.Annotations, error) {
+	var response []ApiClient
+
+	uri, page, err := c.cappedPage(GetApiClientsPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
+
+// GetApiAccessProfiles returns one page of API Platform access profiles, pages start at 1.
+func (c *WorkatoClient) GetApiAccessProfiles(ctx context.Context, pToken string) ([]ApiAccessProfile, string, annotations.Annotations, error) {
+	var response []ApiAccessProfile
+
+	uri, page, err := c.cappedPage(GetApiAccessProfilesPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
+
+// GetAllApiAccessProfiles walks every page of access profiles.
+func (c *WorkatoClient) GetAllApiAccessProfiles(ctx context.Context) ([]ApiAccessProfile, error) {
+	all := make([]ApiAccessProfile, 0)
+
+	token := ""
+
+	for {
+		profiles, nextToken, _, err := c.GetApiAccessProfiles(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, profiles...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	return all, nil
+}
+
+// UpdateApiAccessProfile replaces the name, client and collections of an access profile.
+func (c *WorkatoClient) UpdateApiAccessProfile(ctx context.Context, id int, profile ApiAccessProfileRequest) (annotations.Annotations, error) {
+	pathString := fmt.Sprintf(AccessProfileByIdPath, id)
+
+	return c.doRequest(ctx, http.MethodPut, c.getPath(pathString), nil, profile)
+}
+
+// EnableApiAccessProfile lets the client of the access profile call its collections again.
+func (c *WorkatoClient) EnableApiAccessProfile(ctx context.Context, id int) (annotations.Annotations, error) {
+	pathString := fmt.Sprintf(EnableAccessProfilePath, id)
+
+	return c.doRequest(ctx, http.MethodPut, c.getPath(pathString), nil, nil)
+}
+
+// DisableApiAccessProfile stops the client of the access profile from calling its collections, the profile is kept.
+func (c *WorkatoClient) DisableApiAccessProfile(ctx context.Context, id int) (annotations.Annotations, error) {
+	pathString := fmt.Sprintf(DisableAccessProfilePath, id)
+
+	return c.doRequest(ctx, http.MethodPut, c.getPath(pathString), nil, nil)
+}
+
+// cappedPage returns the url of a page of an endpoint capping per_page at 100, pages start at 1.
+func (c *WorkatoClient) cappedPage(path string, pToken string) (*url.URL, int, error) {
+	page := 1
+	if pToken != "" {
+		var err error
+		page, err = strconv.Atoi(pToken)
+		if err != nil {
+			return nil, 0, ErrInvalidPaginationToken
+		}
+	}
+
+	uri := c.getPath(path)
+
+	query := uri.Query()
+	query.Add("per_page", fmt.Sprintf("%d", c.platformPageLimit))
+	query.Add("page", fmt.Sprintf("%d", page))
+	uri.RawQuery = query.Encode()
+
+	return uri, page, nil
+}
+
+func (c *WorkatoClient) cappedNextToken(count int, page int) string {
+	if count < c.platformPageLimit {
+		return ""
+	}
+
+	return strconv.Itoa(page + 1)
+}
@@ -2,8 +2,14 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"os"
+	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
@@ -18,9 +24,25 @@ var (
 	GetCollaboratorsPath       = "api/members"
 	GetCollaboratorByIdPath    = "api/members/%d/privileges"
 	UpdateCollaboratorByIdPath = "/api/members/%d"
+	DeleteCollaboratorByIdPath = "api/members/%d"
 	GetRolesPath               = "api/roles"
+	RoleByIdPath               = "api/roles/%d"
 	GetProjectsPath            = "api/projects"
 	GetFoldersPath             = "api/folders"
+	GetRecipesPath             = "api/recipes"
+	GetConnectionsPath         = "api/connections"
+	GetApiCollectionsPath      = "api/api_collections"
+	GetApiClientsPath          = "api/api_clients"
+	GetApiAccessProfilesPath   = "api/api_access_profiles"
+	AccessProfileByIdPath      = "api/api_access_profiles/%d"
+	EnableAccessProfilePath    = "api/api_access_profiles/%d/enable"
+	DisableAccessProfilePath   = "api/api_access_profiles/%d/disable"
+	GetOnPremGroupsPath        = "api/on_prem_groups"
+	GetOnPremAgentsPath        = "api/on_prem_groups/%d/agents"
+	GetLookupTablesPath        = "api/lookup_tables"
+	GetDataTablesPath          = "api/data_tables"
+	GetWorkspacePath           = "api/users/me"
+	MemberInvitationsPath      = "api/member_invitations"
 )
 
 type WorkatoClient struct {
@@ -28,9 +50,57 @@ type WorkatoClient struct {
 	baseUrl    *url.URL
 	httpClient *uhttp.BaseHttpClient
 	pageLimit  int
+	// /api/members caps per_page at 100.
+	membersPageLimit int
+	// /api/recipes caps per_page at 100.
+	recipesPageLimit int
+	// API Platform and on-prem endpoints cap per_page at 100.
+	platformPageLimit int
+	requestsPerMinute int
+	maxRetries        int
+	caBundlePath      string
+	proxyUrl          string
+	dryRun            bool
 }
 
-func NewWorkatoClient(ctx context.Context, apiKey, baseUrl string) (*WorkatoClient, error) {
+type Option func(c *WorkatoClient)
+
+// WithRequestsPerMinute limits how many requests the client sends per minute, 0 disables the limit.
+func WithRequestsPerMinute(limit int) Option {
+	return func(c *WorkatoClient) {
+		c.requestsPerMinute = limit
+	}
+}
+
+// WithMaxRetries sets how many times a rate limited request is retried before giving up.
+func WithMaxRetries(retries int) Option {
+	return func(c *WorkatoClient) {
+		c.maxRetries = retries
+	}
+}
+
+// WithCABundle trusts the PEM certificates of the file on top of the system ones.
+func WithCABundle(path string) Option {
+	return func(c *WorkatoClient) {
+		c.caBundlePath = path
+	}
+}
+
+// WithProxy sends every request through the proxy instead of the one in the environment.
+func WithProxy(proxyUrl string) Option {
+	return func(c *WorkatoClient) {
+		c.proxyUrl = proxyUrl
+	}
+}
+
+// WithDryRun refuses every request that changes Workato, only reads are sent.
+func WithDryRun(dryRun bool) Option {
+	return func(c *WorkatoClient) {
+		c.dryRun = dryRun
+	}
+}
+
+func NewWorkatoClient(ctx context.Context, apiKey, baseUrl string, opts ...Option) (*WorkatoClient, error) {
 	parseBaseUrl, err := url.Parse(baseUrl)
 	if err != nil {
 		return nil, err
@@ -40,20 +110,98 @@ func NewWorkatoClient(ctx context.Context, apiKey, baseUrl string) (*WorkatoClie
 		return nil, ErrApiKeyIsEmpty
 	}
 
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	client := &WorkatoClient{
+		baseUrl:           parseBaseUrl,
+		apiKey:            apiKey,
+		pageLimit:         500,
+		membersPageLimit:  100,
+		recipesPageLimit:  100,
+		platformPageLimit: 100,
+		maxRetries:        3,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	httpClient, err := client.newHttpClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	uhttpClient, err := uhttp.NewBaseHttpClientWithContext(ctx, httpClient)
+	var wrapperOptions []uhttp.WrapperOption
+	if client.requestsPerMinute > 0 {
+		wrapperOptions = append(wrapperOptions, uhttp.WithRateLimiter(client.requestsPerMinute, time.Minute))
+	}
+
+	client.httpClient, err = uhttp.NewBaseHttpClientWithContext(ctx, httpClient, wrapperOptions...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &WorkatoClient{
-		httpClient: uhttpClient,
-		baseUrl:    parseBaseUrl,
-		apiKey:     apiKey,
-		pageLimit:  500,
+	return client, nil
+}
+
+func (c *WorkatoClient) BaseUrl() string {
+	return c.baseUrl.String()
+}
+
+func (c *WorkatoClient) tlsConfig() (*tls.Config, error) {
+	if c.caBundlePath == "" {
+		return nil, nil
+	}
+
+	bundle, err := os.ReadFile(c.caBundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("baton-workato: unable to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("baton-workato: CA bundle %s has no PEM certificates", c.caBundlePath)
+	}
+
+	return &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
 	}, nil
 }
+
+// newHttpClient uses the SDK transport, unless a proxy is configured since the SDK transport always reads it from the environment.
+func (c *WorkatoClient) newHttpClient(ctx context.Context) (*http.Client, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.proxyUrl == "" {
+		options := []uhttp.Option{uhttp.WithLogger(true, ctxzap.Extract(ctx))}
+		if tlsConfig != nil {
+			options = append(options, uhttp.WithTLSClientConfig(tlsConfig))
+		}
+
+		return uhttp.NewClient(ctx, options...)
+	}
+
+	proxyUrl, err := url.Parse(c.proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("baton-workato: invalid proxy url: %w", err)
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("baton-workato: unexpected default http transport")
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = http.ProxyURL(proxyUrl)
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
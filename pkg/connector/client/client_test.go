@@ -0,0 +1,295 @@
+package client_test
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/workatotest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func countRequests(server *workatotest.Server, request string) int {
+	count := 0
+	for _, r := range server.Requests() {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestGetAllCollaboratorsPaginates(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+
+	for i := 1; i <= 250; i++ {
+		server.AddCollaborator(client.Collaborator{
+			Id:    i,
+			Name:  fmt.Sprintf("collaborator %d", i),
+			Email: fmt.Sprintf("collaborator%d@example.com", i),
+		})
+	}
+
+	workatoClient := server.Client(t)
+
+	collaborators, err := workatoClient.GetAllCollaborators(ctx)
+	require.NoError(t, err)
+	require.Len(t, collaborators, 250)
+	require.Equal(t, 3, countRequests(server, "GET /api/members"))
+
+	ids := make([]int, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		ids = append(ids, collaborator.Id)
+	}
+	slices.Sort(ids)
+	require.Equal(t, 1, ids[0])
+	require.Equal(t, 250, ids[len(ids)-1])
+	require.Len(t, slices.Compact(ids), 250)
+}
+
+func TestGetCollaboratorsPaginationMismatch(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+
+	server.Fail(http.MethodGet, "/api/members", workatotest.Fault{
+		StatusCode: http.StatusOK,
+		Body:       `{"data":[{"id":1,"name":"collaborator"}],"total":3}`,
+	})
+
+	// A member removed while the pages are read ends the pagination at the first page that isn't full.
+	collaborators, token, _, err := server.Client(t).GetCollaborators(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, collaborators, 1)
+	require.Empty(t, token)
+}
+
+func TestGetCollaboratorsWithoutTotal(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+
+	server.Fail(http.MethodGet, "/api/members", workatotest.Fault{
+		StatusCode: http.StatusOK,
+		Body:       `{"data":[{"id":1,"name":"collaborator"}]}`,
+	})
+
+	collaborators, token, _, err := server.Client(t).GetCollaborators(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, collaborators, 1)
+	require.Empty(t, token)
+}
+
+func TestRequestRetriesWhenRateLimited(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+	server.AddRole(client.Role{Id: 1, Name: "Builders"})
+
+	server.Fail(http.MethodGet, "/api/roles", workatotest.Fault{
+		StatusCode: http.StatusTooManyRequests,
+		Body:       `{"message":"Too many requests"}`,
+		Header:     http.Header{"Retry-After": []string{"0"}},
+		Times:      1,
+	})
+
+	roles, _, _, err := server.Client(t).GetRoles(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	require.Equal(t, 2, countRequests(server, "GET /api/roles"))
+}
+
+func TestRequestGivesUpWhenRateLimited(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+
+	server.Fail(http.MethodGet, "/api/roles", workatotest.Fault{
+		StatusCode: http.StatusTooManyRequests,
+		Body:       `{"message":"Too many requests"}`,
+	})
+
+	_, _, _, err := server.Client(t, client.WithMaxRetries(0)).GetRoles(ctx, "")
+	require.Error(t, err)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 1, countRequests(server, "GET /api/roles"))
+}
+
+func TestRequestRetriesUnavailableOnlyWhenIdempotent(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+	server.AddRole(client.Role{Id: 1, Name: "Builders"})
+
+	unavailable := workatotest.Fault{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       `{"message":"Service unavailable"}`,
+		Times:      1,
+	}
+	server.Fail(http.MethodGet, "/api/roles", unavailable)
+	server.Fail(http.MethodPost, "/api/roles", unavailable)
+
+	workatoClient := server.Client(t)
+
+	roles, _, _, err := workatoClient.GetRoles(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	require.Equal(t, 2, countRequests(server, "GET /api/roles"))
+
+	// The role may have been created before the 503, sending it again could create it twice.
+	_, _, err = workatoClient.CreateRole(ctx, client.RoleRequest{Name: "Operators"})
+	require.Error(t, err)
+	require.Equal(t, 1, countRequests(server, "POST /api/roles"))
+}
+
+func TestDryRunOnlySendsReads(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+	server.AddRole(client.Role{Id: 1, Name: "Builders"})
+
+	workatoClient := server.Client(t, client.WithDryRun(true))
+
+	roles, _, _, err := workatoClient.GetRoles(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+
+	_, _, err = workatoClient.CreateRole(ctx, client.RoleRequest{Name: "Operators"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = workatoClient.DeleteRole(ctx, 1)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	require.Zero(t, countRequests(server, "POST /api/roles"))
+	require.Zero(t, countRequests(server, "DELETE /api/roles/1"))
+}
+
+func TestRequestErrors(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		statusCode int
+		code       codes.Code
+	}{
+		{name: "forbidden", statusCode: http.StatusForbidden, code: codes.PermissionDenied},
+		{name: "not found", statusCode: http.StatusNotFound, code: codes.NotFound},
+		{name: "server error", statusCode: http.StatusInternalServerError, code: codes.Unavailable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := workatotest.NewServer(t)
+			server.Fail(http.MethodGet, "/api/folders", workatotest.Fault{
+				StatusCode: c.statusCode,
+				Body:       `{"message":"injected"}`,
+			})
+
+			_, _, _, err := server.Client(t).GetFolders(ctx, nil, "")
+			require.Error(t, err)
+			require.Equal(t, c.code, status.Code(err))
+
+			var workatoErr *client.WorkatoError
+			require.ErrorAs(t, err, &workatoErr)
+			require.Equal(t, "/api/folders", workatoErr.Path)
+			require.Equal(t, "injected", workatoErr.Message)
+		})
+	}
+}
+
+func TestUpdateCollaboratorRoles(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+	server.AddCollaborator(
+		client.Collaborator{Id: 1, Name: "collaborator"},
+		&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Operator"},
+	)
+
+	workatoClient := server.Client(t)
+
+	roles := []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}
+
+	_, err := workatoClient.UpdateCollaboratorRoles(ctx, 1, roles)
+	require.NoError(t, err)
+	require.Equal(t, roles, server.CollaboratorRoles(1))
+
+	_, err = workatoClient.UpdateCollaboratorRoles(ctx, 2, roles)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClientTrustsCABundle(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":1,"name":"Builders"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, certificate, 0600))
+
+	untrusted, err := client.NewWorkatoClient(ctx, workatotest.ApiKey, server.URL, client.WithMaxRetries(0))
+	require.NoError(t, err)
+
+	_, _, _, err = untrusted.GetRoles(ctx, "")
+	require.Error(t, err)
+
+	trusted, err := client.NewWorkatoClient(ctx, workatotest.ApiKey, server.URL, client.WithCABundle(bundle))
+	require.NoError(t, err)
+
+	roles, _, _, err := trusted.GetRoles(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+}
+
+func TestClientRejectsInvalidCABundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0600))
+
+	_, err := client.NewWorkatoClient(context.Background(), workatotest.ApiKey, "https://www.workato.com", client.WithCABundle(bundle))
+	require.Error(t, err)
+}
+
+func TestClientUsesProxy(t *testing.T) {
+	ctx := context.Background()
+	server := workatotest.NewServer(t)
+	server.AddRole(client.Role{Id: 1, Name: "Builders"})
+
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+
+		r.URL.Scheme = "http"
+		r.URL.Host = server.URL()[len("http://"):]
+		r.RequestURI = ""
+
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "true")
+	workatoClient, err := client.NewWorkatoClient(ctx, workatotest.ApiKey, "http://workato.invalid", client.WithProxy(proxy.URL))
+	require.NoError(t, err)
+
+	roles, _, _, err := workatoClient.GetRoles(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	require.Equal(t, []string{"workato.invalid"}, hosts)
+}
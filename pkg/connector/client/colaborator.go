@@ -4,63 +4,167 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func (c *WorkatoClient) GetCollaborators(ctx context.Context) ([]Collaborator, error) {
+// GetCollaborators returns one page of workspace members.
+// Members pages start at 1 https://docs.workato.com/workato-api/team.html#list-collaborators
+func (c *WorkatoClient) GetCollaborators(ctx context.Context, pToken string) ([]Collaborator, string, annotations.Annotations, error) {
 	var response CommonPagination[Collaborator]
+	var err error
+
+	page := 1
+	if pToken != "" {
+		page, err = strconv.Atoi(pToken)
+		if err != nil {
+			return nil, "", nil, ErrInvalidPaginationToken
+		}
+	}
+
+	uri := c.getPath(GetCollaboratorsPath)
+
+	query := uri.Query()
+	query.Add("per_page", fmt.Sprintf("%d", c.membersPageLimit))
+	query.Add("page", fmt.Sprintf("%d", page))
+	uri.RawQuery = query.Encode()
 
-	err := c.doRequest(ctx, http.MethodGet, c.getPath(GetCollaboratorsPath), &response, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", annos, err
+	}
+
+	token := nextTokenFromTotal(ctx, uri.Path, page, c.membersPageLimit, len(response.Data), response.Total)
+
+	return response.Data, token, annos, nil
+}
+
+// GetAllCollaborators walks every page of workspace members.
+func (c *WorkatoClient) GetAllCollaborators(ctx context.Context) ([]Collaborator, error) {
+	all := make([]Collaborator, 0)
+
+	token := ""
+
+	for {
+		collaborators, nextToken, _, err := c.GetCollaborators(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, collaborators...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
 	}
 
-	return response.Data, nil
+	return all, nil
 }
 
-func (c *WorkatoClient) GetCollaboratorPrivileges(ctx context.Context, id int) ([]*CollaboratorPrivilege, error) {
+func (c *WorkatoClient) GetCollaboratorPrivileges(ctx context.Context, id int) ([]*CollaboratorPrivilege, annotations.Annotations, error) {
 	var response CommonPagination[*CollaboratorPrivilege]
 
 	pathString := fmt.Sprintf(GetCollaboratorByIdPath, id)
 
-	err := c.doRequest(ctx, http.MethodGet, c.getPath(pathString), &response, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, c.getPath(pathString), &response, nil)
 	if err != nil {
-		return nil, err
+		return nil, annos, err
 	}
 
-	if len(response.Data) != 1 {
-		return nil, status.Errorf(codes.NotFound, "baton-workato: expected 1 collaborator, got %d", len(response.Data))
+	// There is one entry per environment the collaborator has a role in.
+	if len(response.Data) == 0 {
+		return nil, annos, status.Errorf(codes.NotFound, "baton-workato: collaborator %d has no privileges", id)
+	}
+
+	return response.Data, annos, nil
+}
+
+// envRoleRequest is needed because the json payload it's different https://docs.workato.com/workato-api/team.html#update-collaborator-roles
+type envRoleRequest struct {
+	EnvironmentType string `json:"environment_type"`
+	RoleName        string `json:"name"`
+}
+
+func toEnvRoleRequests(roles []SimpleRole) []envRoleRequest {
+	var rolesRequest []envRoleRequest
+	for _, role := range roles {
+		rolesRequest = append(rolesRequest, envRoleRequest(role))
 	}
 
-	return response.Data, nil
+	return rolesRequest
 }
 
-func (c *WorkatoClient) UpdateCollaboratorRoles(ctx context.Context, id int, roles []SimpleRole) error {
+func (c *WorkatoClient) UpdateCollaboratorRoles(ctx context.Context, id int, roles []SimpleRole) (annotations.Annotations, error) {
 	pathString := fmt.Sprintf(UpdateCollaboratorByIdPath, id)
 
-	// Needs this because the json payload it's different https://docs.workato.com/workato-api/team.html#update-collaborator-roles
-	type SimpleRoleRequest struct {
-		EnvironmentType string `json:"environment_type"`
-		RoleName        string `json:"name"`
+	body := struct {
+		EnvRoles []envRoleRequest `json:"env_roles"`
+	}{
+		EnvRoles: toEnvRoleRequests(roles),
 	}
 
-	var rolesRequest []SimpleRoleRequest
-	for _, role := range roles {
-		rolesRequest = append(rolesRequest, SimpleRoleRequest(role))
+	annos, err := c.doRequest(ctx, http.MethodPut, c.getPath(pathString), nil, body)
+	if err != nil {
+		return annos, err
 	}
 
+	return annos, nil
+}
+
+// InviteCollaborator sends an invitation to join the workspace https://docs.workato.com/workato-api/team.html#invite-a-collaborator
+// The collaborator is only listed as a member once the invitation is accepted.
+func (c *WorkatoClient) InviteCollaborator(ctx context.Context, name, email string, roles []SimpleRole) (annotations.Annotations, error) {
 	body := struct {
-		EnvRoles []SimpleRoleRequest `json:"env_roles"`
+		Name     string           `json:"name"`
+		Email    string           `json:"email"`
+		EnvRoles []envRoleRequest `json:"env_roles"`
 	}{
-		EnvRoles: rolesRequest,
+		Name:     name,
+		Email:    email,
+		EnvRoles: toEnvRoleRequests(roles),
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.getPath(MemberInvitationsPath), nil, body)
+}
+
+// GetMemberInvitations returns one page of pending invitations, pages start at 1 like members.
+func (c *WorkatoClient) GetMemberInvitations(ctx context.Context, pToken string) ([]MemberInvitation, string, annotations.Annotations, error) {
+	var response CommonPagination[MemberInvitation]
+	var err error
+
+	page := 1
+	if pToken != "" {
+		page, err = strconv.Atoi(pToken)
+		if err != nil {
+			return nil, "", nil, ErrInvalidPaginationToken
+		}
 	}
 
-	err := c.doRequest(ctx, http.MethodPut, c.getPath(pathString), nil, body)
+	uri := c.getPath(MemberInvitationsPath)
+
+	query := uri.Query()
+	query.Add("per_page", fmt.Sprintf("%d", c.membersPageLimit))
+	query.Add("page", fmt.Sprintf("%d", page))
+	uri.RawQuery = query.Encode()
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
 	if err != nil {
-		return err
+		return nil, "", annos, err
 	}
 
-	return nil
+	token := nextTokenFromTotal(ctx, uri.Path, page, c.membersPageLimit, len(response.Data), response.Total)
+
+	return response.Data, token, annos, nil
+}
+
+// DeleteCollaborator removes the member from the workspace.
+func (c *WorkatoClient) DeleteCollaborator(ctx context.Context, id int) (annotations.Annotations, error) {
+	pathString := fmt.Sprintf(DeleteCollaboratorByIdPath, id)
+
+	return c.doRequest(ctx, http.MethodDelete, c.getPath(pathString), nil, nil)
 }
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// GetConnections returns the connections directly in a folder, the endpoint isn't paginated.
+// https://docs.workato.com/workato-api/connections.html#list-connections
+func (c *WorkatoClient) GetConnections(ctx context.Context, folderId int) ([]Connection, annotations.Annotations, error) {
+	var response []Connection
+
+	uri := c.getPath(GetConnectionsPath)
+
+	query := uri.Query()
+	query.Add("folder_id", fmt.Sprintf("%d", folderId))
+	uri.RawQuery = query.Encode()
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return response, annos, nil
+}
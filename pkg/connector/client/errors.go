@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WorkatoError is returned for every non 2xx response of the Workato API.
+// It implements GRPCStatus so the SDK reports the mapped code back to ConductorOne.
+type WorkatoError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Message    string
+	// ErrorCodes are the codes Workato sends in the "errors" list of the body.
+	ErrorCodes []string
+
+	err error
+}
+
+func (e *WorkatoError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("baton-workato: %s %s failed with status %d", e.Method, e.Path, e.StatusCode))
+
+	if e.Message != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Message)
+	}
+
+	if len(e.ErrorCodes) > 0 {
+		sb.WriteString(fmt.Sprintf(" (workato error codes: %s)", strings.Join(e.ErrorCodes, ", ")))
+	}
+
+	return sb.String()
+}
+
+func (e *WorkatoError) Unwrap() error {
+	return e.err
+}
+
+func (e *WorkatoError) Code() codes.Code {
+	return codeFromStatusCode(e.StatusCode)
+}
+
+func (e *WorkatoError) GRPCStatus() *status.Status {
+	return status.New(e.Code(), e.Error())
+}
+
+func codeFromStatusCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	}
+
+	if statusCode >= 500 && statusCode <= 599 {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// ErrorCode accepts both numeric and string codes, Workato uses both depending on the endpoint.
+type ErrorCode string
+
+func (e *ErrorCode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*e = ErrorCode(value)
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+
+	*e = ErrorCode(number.String())
+	return nil
+}
+
+func newWorkatoError(method, path string, resp *http.Response, originalErr error) *WorkatoError {
+	workatoErr := &WorkatoError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Path:       "/" + strings.TrimPrefix(path, "/"),
+		err:        originalErr,
+	}
+
+	bytes, err := io.ReadAll(resp.Body)
+	if err != nil || len(bytes) == 0 {
+		workatoErr.Message = http.StatusText(resp.StatusCode)
+		return workatoErr
+	}
+
+	var cErr ApiError
+	if err := json.Unmarshal(bytes, &cErr); err != nil {
+		workatoErr.Message = http.StatusText(resp.StatusCode)
+		return workatoErr
+	}
+
+	messages := make([]string, 0)
+	if cErr.Message != "" {
+		messages = append(messages, cErr.Message)
+	}
+
+	if cErr.ErrorMessage != "" {
+		messages = append(messages, cErr.ErrorMessage)
+	}
+
+	for _, detail := range cErr.Errors {
+		if detail.Code != "" {
+			workatoErr.ErrorCodes = append(workatoErr.ErrorCodes, string(detail.Code))
+		}
+
+		switch {
+		case detail.Title != "" && detail.Detail != "":
+			messages = append(messages, detail.Title+": "+detail.Detail)
+		case detail.Title != "":
+			messages = append(messages, detail.Title)
+		case detail.Detail != "":
+			messages = append(messages, detail.Detail)
+		}
+	}
+
+	if len(messages) == 0 {
+		messages = append(messages, http.StatusText(resp.StatusCode))
+	}
+
+	workatoErr.Message = strings.Join(messages, "; ")
+
+	return workatoErr
+}
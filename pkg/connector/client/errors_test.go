@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewWorkatoError(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       string
+
+		code       codes.Code
+		message    string
+		errorCodes []string
+	}{
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"message":"Unauthorized"}`,
+			code:       codes.Unauthenticated,
+			message:    "Unauthorized",
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"error":"API client is missing privileges"}`,
+			code:       codes.PermissionDenied,
+			message:    "API client is missing privileges",
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"message":"Not found"}`,
+			code:       codes.NotFound,
+			message:    "Not found",
+		},
+		{
+			name:       "conflict",
+			statusCode: http.StatusConflict,
+			body:       `{"errors":[{"code":"role_in_use","title":"Conflict"}]}`,
+			code:       codes.FailedPrecondition,
+			message:    "Conflict",
+			errorCodes: []string{"role_in_use"},
+		},
+		{
+			name:       "unprocessable",
+			statusCode: http.StatusUnprocessableEntity,
+			body:       `{"errors":[{"code":422,"title":"Invalid role","detail":"name is taken"}]}`,
+			code:       codes.InvalidArgument,
+			message:    "Invalid role: name is taken",
+			errorCodes: []string{"422"},
+		},
+		{
+			name:       "server error without body",
+			statusCode: http.StatusBadGateway,
+			code:       codes.Unavailable,
+			message:    "Bad Gateway",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: c.statusCode,
+				Body:       io.NopCloser(bytes.NewBufferString(c.body)),
+			}
+
+			original := errors.New("original")
+			err := newWorkatoError(http.MethodGet, "/api/roles", resp, original)
+
+			require.Equal(t, c.message, err.Message)
+			require.Equal(t, c.errorCodes, err.ErrorCodes)
+			require.Equal(t, c.code, status.Code(err))
+			require.ErrorIs(t, err, original)
+			require.Contains(t, err.Error(), "/api/roles")
+		})
+	}
+}
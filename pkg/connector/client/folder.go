@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 )
 
-func (c *WorkatoClient) GetFolders(ctx context.Context, parentId *int, pToken string) ([]Folder, string, error) {
+func (c *WorkatoClient) GetFolders(ctx context.Context, parentId *int, pToken string) ([]Folder, string, annotations.Annotations, error) {
 	var response []Folder
 	var err error
 
@@ -15,7 +17,7 @@ func (c *WorkatoClient) GetFolders(ctx context.Context, parentId *int, pToken st
 	if pToken != "" {
 		page, err = strconv.Atoi(pToken)
 		if err != nil {
-			return nil, "", ErrInvalidPaginationToken
+			return nil, "", nil, ErrInvalidPaginationToken
 		}
 	}
 
@@ -31,10 +33,10 @@ func (c *WorkatoClient) GetFolders(ctx context.Context, parentId *int, pToken st
 
 	uri.RawQuery = query.Encode()
 
-	err = c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", annos, err
 	}
 
-	return response, nextToken(c, response, page), nil
+	return response, nextToken(c, response, page), annos, nil
 }
@@ -2,14 +2,22 @@ package client
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -24,67 +32,162 @@ var (
 		"sg": "https://app.sg.workato.com",
 		"au": "https://app.au.workato.com",
 	}
+
+	// Headers Workato uses to tell when a rate limited request can be retried.
+	rateLimitResetHeaders = []string{
+		"Retry-After",
+		"X-RateLimit-Reset",
+		"RateLimit-Reset",
+	}
+)
+
+const (
+	baseRetryDelay = time.Second
+	maxRetryDelay  = 2 * time.Minute
 )
 
 func (c *WorkatoClient) getPath(path string) *url.URL {
 	return c.baseUrl.JoinPath(path)
 }
 
-func getError(originalErr error, resp *http.Response) error {
-	bytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Join(originalErr, err)
-	}
+func (c *WorkatoClient) doRequest(ctx context.Context, method string, urlAddress *url.URL, res interface{}, body interface{}) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
 
-	var cErr ApiError
-	err = json.Unmarshal(bytes, &cErr)
-	if err != nil {
-		return errors.Join(originalErr, err)
+	if c.dryRun && method != http.MethodGet {
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-workato: dry run, %s %s was not sent", method, urlAddress.Path)
 	}
 
-	return errors.Join(originalErr, errors.New(cErr.Message))
+	for attempt := 0; ; attempt++ {
+		req, err := c.httpClient.NewRequest(
+			ctx,
+			method,
+			urlAddress,
+			uhttp.WithBearerToken(c.apiKey),
+			uhttp.WithJSONBody(body),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var options []uhttp.DoOption
+
+		if res != nil {
+			options = append(options, uhttp.WithResponse(&res))
+		}
+
+		resp, err := c.httpClient.Do(req, options...)
+
+		if resp == nil {
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, errors.New("baton-workato: response is nil and error is nil, this should never happen, might be a bug in the http client")
+		}
+
+		if isRetryable(method, resp.StatusCode) && attempt < c.maxRetries {
+			_ = resp.Body.Close()
+
+			delay := retryDelay(resp, attempt)
+
+			l.Warn(
+				"baton-workato: request was rate limited, retrying",
+				zap.String("path", urlAddress.Path),
+				zap.Int("status_code", resp.StatusCode),
+				zap.Int("attempt", attempt+1),
+				zap.Duration("delay", delay),
+			)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+
+			continue
+		}
+
+		annos := rateLimitAnnotations(resp)
+
+		if err != nil {
+			if resp.StatusCode >= http.StatusBadRequest {
+				err = newWorkatoError(method, urlAddress.Path, resp, err)
+			}
+
+			_ = resp.Body.Close()
+			return annos, err
+		}
+
+		_ = resp.Body.Close()
+
+		if method != http.MethodGet {
+			c.InvalidateCache(ctx)
+		}
+
+		return annos, nil
+	}
 }
 
-func (c *WorkatoClient) doRequest(ctx context.Context, method string, urlAddress *url.URL, res interface{}, body interface{}) error {
-	var (
-		resp *http.Response
-		err  error
-	)
-
-	req, err := c.httpClient.NewRequest(
-		ctx,
-		method,
-		urlAddress,
-		uhttp.WithBearerToken(c.apiKey),
-		uhttp.WithJSONBody(body),
-	)
+// InvalidateCache drops the GET responses cached by the SDK so the next reads hit Workato.
+// Writes call it since cached responses are stale once Workato changed, callers use it before reading state they are about to change.
+// The SDK keeps the caches per process, so every client is affected.
+func (c *WorkatoClient) InvalidateCache(ctx context.Context) {
+	err := uhttp.ClearCaches(ctx)
 	if err != nil {
-		return err
+		ctxzap.Extract(ctx).Warn("baton-workato: unable to clear the http cache", zap.Error(err))
 	}
+}
 
-	var options []uhttp.DoOption
-
-	if res != nil {
-		options = append(options, uhttp.WithResponse(&res))
+// isRetryable tells if a request can be sent again. A rate limited request never reached Workato,
+// a 503 may come from a proxy after the write landed, so only idempotent requests are retried then.
+func isRetryable(method string, statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
 	}
 
-	resp, err = c.httpClient.Do(req, options...)
+	return statusCode == http.StatusServiceUnavailable && method != http.MethodPost
+}
 
-	if resp == nil {
-		if err != nil {
-			return err
+// retryDelay prefers the reset time Workato sends back, falling back to an exponential backoff.
+// Jitter is added so parallel syncs don't retry in lockstep.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	delay := min(baseRetryDelay<<attempt, maxRetryDelay)
+
+	if hasRateLimitReset(resp.Header) {
+		description, err := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header)
+		if err == nil && description.GetResetAt() != nil {
+			if untilReset := time.Until(description.GetResetAt().AsTime()); untilReset > 0 {
+				delay = min(untilReset, maxRetryDelay)
+			}
 		}
+	}
 
-		return errors.New("baton-workato: response is nil and error is nil, this should never happen, might be a bug in the http client")
+	//nolint:gosec // jitter doesn't need a cryptographically secure source
+	return delay + rand.N(delay/4+1)
+}
+
+func hasRateLimitReset(header http.Header) bool {
+	for _, name := range rateLimitResetHeaders {
+		if header.Get(name) != "" {
+			return true
+		}
 	}
 
-	defer resp.Body.Close()
+	return false
+}
+
+// rateLimitAnnotations returns the rate limit state of the response so the syncer can pace itself.
+func rateLimitAnnotations(resp *http.Response) annotations.Annotations {
+	description, err := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header)
+	if err != nil || description == nil {
+		return nil
+	}
 
-	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
-		return getError(err, resp)
+	if description.Limit == 0 && description.Status == v2.RateLimitDescription_STATUS_UNSPECIFIED {
+		return nil
 	}
 
-	return nil
+	return annotations.New(description)
 }
 
 func nextToken[T any](c *WorkatoClient, response []T, page int) string {
@@ -96,3 +199,25 @@ func nextToken[T any](c *WorkatoClient, response []T, page int) string {
 
 	return token
 }
+
+// nextTokenFromTotal is used by endpoints that wrap their data in CommonPagination, page is 1-based.
+// The last page is the first one that isn't full, or the one reaching the reported total. Members can be added or removed
+// while the pages are read, and some endpoints don't report a total, so a mismatch with the total is only logged.
+func nextTokenFromTotal(ctx context.Context, path string, page, perPage, count, total int) string {
+	seen := (page-1)*perPage + count
+
+	if count == perPage && (total == 0 || seen < total) {
+		return strconv.Itoa(page + 1)
+	}
+
+	if total != 0 && seen != total {
+		ctxzap.Extract(ctx).Warn(
+			"baton-workato: pagination did not match the reported total",
+			zap.String("path", path),
+			zap.Int("seen", seen),
+			zap.Int("total", total),
+		)
+	}
+
+	return ""
+}
@@ -3,7 +3,23 @@ package client
 import "time"
 
 type ApiError struct {
-	Message string `json:"message"`
+	Message      string           `json:"message"`
+	ErrorMessage string           `json:"error"`
+	Errors       []ApiErrorDetail `json:"errors"`
+}
+
+type ApiErrorDetail struct {
+	Code   ErrorCode `json:"code"`
+	Title  string    `json:"title"`
+	Detail string    `json:"detail"`
+}
+
+type Workspace struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PlanId       string `json:"plan_id"`
+	RootFolderId int    `json:"root_folder_id"`
 }
 
 type CommonPagination[T any] struct {
@@ -11,6 +27,11 @@ type CommonPagination[T any] struct {
 	Total int `json:"total"`
 }
 
+// ItemsPagination wraps the data of endpoints that don't report a total, like recipes.
+type ItemsPagination[T any] struct {
+	Items []T `json:"items"`
+}
+
 type SimpleRole struct {
 	EnvironmentType string `json:"environment_type"`
 	RoleName        string `json:"role_name"`
@@ -37,6 +58,14 @@ type Collaborator struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+type MemberInvitation struct {
+	Id        int          `json:"id"`
+	Name      string       `json:"name"`
+	Email     string       `json:"email"`
+	Roles     []SimpleRole `json:"roles"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
 type CollaboratorPrivilege struct {
 	EnvironmentType string              `json:"environment_type"`
 	Name            string              `json:"name"`
@@ -61,6 +90,24 @@ type Role struct {
 	Privileges  map[string][]string `json:"privileges"`
 }
 
+// RoleRequest is the payload to create or update a custom role.
+type RoleRequest struct {
+	Name        string              `json:"name"`
+	Inheritable bool                `json:"inheritable"`
+	FolderIDs   []int               `json:"folder_ids"`
+	Privileges  map[string][]string `json:"privileges"`
+}
+
+// Request returns the payload that keeps the role as it is.
+func (r *Role) Request() RoleRequest {
+	return RoleRequest{
+		Name:        r.Name,
+		Inheritable: r.Inheritable,
+		FolderIDs:   r.FolderIDs,
+		Privileges:  r.Privileges,
+	}
+}
+
 type Folder struct {
 	Id        int       `json:"id"`
 	Name      string    `json:"name"`
@@ -75,3 +122,140 @@ type Project struct {
 	FolderId    int    `json:"folder_id"`
 	Name        string `json:"name"`
 }
+
+// RecipeConnection is an application step of a recipe, AccountId is the connection it runs with.
+type RecipeConnection struct {
+	Keyword   string `json:"keyword"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	AccountId *int   `json:"account_id"`
+}
+
+type Recipe struct {
+	Id                 int                `json:"id"`
+	UserId             int                `json:"user_id"`
+	Name               string             `json:"name"`
+	Description        string             `json:"description"`
+	FolderId           int                `json:"folder_id"`
+	Running            bool               `json:"running"`
+	StopCause          string             `json:"stop_cause"`
+	LastRunAt          *time.Time         `json:"last_run_at"`
+	StoppedAt          *time.Time         `json:"stopped_at"`
+	JobSucceededCount  int                `json:"job_succeeded_count"`
+	JobFailedCount     int                `json:"job_failed_count"`
+	AuthorName         string             `json:"author_name"`
+	VersionNo          int                `json:"version_no"`
+	TriggerApplication string             `json:"trigger_application"`
+	Config             []RecipeConnection `json:"config"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+type Connection struct {
+	Id                   int        `json:"id"`
+	Name                 string     `json:"name"`
+	Application          string     `json:"application"`
+	Provider             string     `json:"provider"`
+	Description          string     `json:"description"`
+	FolderId             int        `json:"folder_id"`
+	ParentId             *int       `json:"parent_id"`
+	ExternalId           string     `json:"external_id"`
+	AuthorizationStatus  string     `json:"authorization_status"`
+	AuthorizationError   string     `json:"authorization_error"`
+	AuthorizedAt         *time.Time `json:"authorized_at"`
+	ConnectionLostAt     *time.Time `json:"connection_lost_at"`
+	ConnectionLostReason string     `json:"connection_lost_reason"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	// OnPremGroupId is set when the connection reaches its system through an on-prem agent group.
+	OnPremGroupId *int `json:"on_prem_group_id"`
+}
+
+type ApiCollection struct {
+	Id         int       `json:"id"`
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Url        string    `json:"url"`
+	ApiSpecUrl string    `json:"api_spec_url"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ApiClient struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ApiAccessProfile gives an API client access to collections, the auth token Workato returns is deliberately not decoded.
+type ApiAccessProfile struct {
+	Id               int       `json:"id"`
+	Name             string    `json:"name"`
+	ApiClientId      int       `json:"api_client_id"`
+	ApiCollectionIds []int     `json:"api_collection_ids"`
+	Active           bool      `json:"active"`
+	AuthType         string    `json:"auth_type"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ApiAccessProfileRequest is the payload to update an access profile.
+type ApiAccessProfileRequest struct {
+	Name             string `json:"name"`
+	ApiClientId      int    `json:"api_client_id"`
+	ApiCollectionIds []int  `json:"api_collection_ids"`
+}
+
+// Request returns the payload that keeps the access profile as it is.
+func (p *ApiAccessProfile) Request() ApiAccessProfileRequest {
+	return ApiAccessProfileRequest{
+		Name:             p.Name,
+		ApiClientId:      p.ApiClientId,
+		ApiCollectionIds: p.ApiCollectionIds,
+	}
+}
+
+type OnPremGroup struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OnPremAgent struct {
+	Id            int       `json:"id"`
+	Name          string    `json:"name"`
+	OnPremGroupId int       `json:"on_prem_group_id"`
+	Status        string    `json:"status"`
+	Os            string    `json:"os"`
+	Version       string    `json:"version"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// LookupTable belongs to a project, FolderId is only set by workspaces that keep lookup tables in a folder of the project.
+type LookupTable struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	ProjectId int       `json:"project_id"`
+	FolderId  int       `json:"folder_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DataTableColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DataTable ids are UUIDs, unlike the other Workato assets.
+type DataTable struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	FolderId  int               `json:"folder_id"`
+	Schema    []DataTableColumn `json:"schema"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
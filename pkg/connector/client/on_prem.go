@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// GetOnPremGroups returns one page of on-prem agent groups, pages start at 1.
+// https://docs.workato.com/workato-api/on-prem.html
+func (c *WorkatoClient) GetOnPremGroups(ctx context.Context, pToken string) ([]OnPremGroup, string, annotations.Annotations, error) {
+	var response []OnPremGroup
+
+	uri, page, err := c.cappedPage(GetOnPremGroupsPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
+
+// GetOnPremAgents returns one page of the agents of an on-prem group, pages start at 1.
+func (c *WorkatoClient) GetOnPremAgents(ctx context.Context, groupId int, pToken string) ([]OnPremAgent, string, annotations.Annotations, error) {
+	var response []OnPremAgent
+
+	uri, page, err := c.cappedPage(fmt.Sprintf(GetOnPremAgentsPath, groupId), pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
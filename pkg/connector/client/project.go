@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 )
 
-func (c *WorkatoClient) GetProjects(ctx context.Context, pToken string) ([]Project, string, error) {
+func (c *WorkatoClient) GetProjects(ctx context.Context, pToken string) ([]Project, string, annotations.Annotations, error) {
 	var response []Project
 	var err error
 
@@ -15,7 +17,7 @@ func (c *WorkatoClient) GetProjects(ctx context.Context, pToken string) ([]Proje
 	if pToken != "" {
 		page, err = strconv.Atoi(pToken)
 		if err != nil {
-			return nil, "", ErrInvalidPaginationToken
+			return nil, "", nil, ErrInvalidPaginationToken
 		}
 	}
 
@@ -26,10 +28,34 @@ func (c *WorkatoClient) GetProjects(ctx context.Context, pToken string) ([]Proje
 	query.Add("page", fmt.Sprintf("%d", page))
 	uri.RawQuery = query.Encode()
 
-	err = c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", annos, err
+	}
+
+	return response, nextToken(c, response, page), annos, nil
+}
+
+// GetAllProjects walks every page of projects.
+func (c *WorkatoClient) GetAllProjects(ctx context.Context) ([]Project, error) {
+	all := make([]Project, 0)
+
+	token := ""
+
+	for {
+		projects, nextToken, _, err := c.GetProjects(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, projects...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
 	}
 
-	return response, nextToken(c, response, page), nil
+	return all, nil
 }
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// GetRecipes returns one page of the recipes directly in a folder.
+// Recipes pages start at 1 https://docs.workato.com/workato-api/recipes.html#list-recipes
+func (c *WorkatoClient) GetRecipes(ctx context.Context, folderId int, pToken string) ([]Recipe, string, annotations.Annotations, error) {
+	var response ItemsPagination[Recipe]
+	var err error
+
+	page := 1
+	if pToken != "" {
+		page, err = strconv.Atoi(pToken)
+		if err != nil {
+			return nil, "", nil, ErrInvalidPaginationToken
+		}
+	}
+
+	uri := c.getPath(GetRecipesPath)
+
+	query := uri.Query()
+	query.Add("folder_id", fmt.Sprintf("%d", folderId))
+	query.Add("per_page", fmt.Sprintf("%d", c.recipesPageLimit))
+	query.Add("page", fmt.Sprintf("%d", page))
+	uri.RawQuery = query.Encode()
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	token := ""
+	if len(response.Items) == c.recipesPageLimit {
+		token = strconv.Itoa(page + 1)
+	}
+
+	return response.Items, token, annos, nil
+}
+
+// GetAllRecipes walks every page of the recipes directly in a folder.
+func (c *WorkatoClient) GetAllRecipes(ctx context.Context, folderId int) ([]Recipe, error) {
+	all := make([]Recipe, 0)
+
+	token := ""
+
+	for {
+		recipes, nextToken, _, err := c.GetRecipes(ctx, folderId, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, recipes...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	return all, nil
+}
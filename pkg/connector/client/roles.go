@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 )
 
-func (c *WorkatoClient) GetRoles(ctx context.Context, pToken string) ([]Role, string, error) {
+func (c *WorkatoClient) GetRoles(ctx context.Context, pToken string) ([]Role, string, annotations.Annotations, error) {
 	var response []Role
 	var err error
 
@@ -15,7 +17,7 @@ func (c *WorkatoClient) GetRoles(ctx context.Context, pToken string) ([]Role, st
 	if pToken != "" {
 		page, err = strconv.Atoi(pToken)
 		if err != nil {
-			return nil, "", ErrInvalidPaginationToken
+			return nil, "", nil, ErrInvalidPaginationToken
 		}
 	}
 
@@ -26,10 +28,66 @@ func (c *WorkatoClient) GetRoles(ctx context.Context, pToken string) ([]Role, st
 	query.Add("page", fmt.Sprintf("%d", page))
 	uri.RawQuery = query.Encode()
 
-	err = c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", annos, err
 	}
 
-	return response, nextToken(c, response, page), nil
+	return response, nextToken(c, response, page), annos, nil
+}
+
+// GetAllRoles walks every page of custom roles.
+func (c *WorkatoClient) GetAllRoles(ctx context.Context) ([]Role, error) {
+	all := make([]Role, 0)
+
+	token := ""
+
+	for {
+		roles, nextToken, _, err := c.GetRoles(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, roles...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	return all, nil
+}
+
+// CreateRole creates a custom collaborator role.
+func (c *WorkatoClient) CreateRole(ctx context.Context, role RoleRequest) (*Role, annotations.Annotations, error) {
+	var response Role
+
+	annos, err := c.doRequest(ctx, http.MethodPost, c.getPath(GetRolesPath), &response, role)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &response, annos, nil
+}
+
+// UpdateRole replaces the name, privileges and folders of a custom role.
+func (c *WorkatoClient) UpdateRole(ctx context.Context, id int, role RoleRequest) (*Role, annotations.Annotations, error) {
+	var response Role
+
+	pathString := fmt.Sprintf(RoleByIdPath, id)
+
+	annos, err := c.doRequest(ctx, http.MethodPut, c.getPath(pathString), &response, role)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &response, annos, nil
+}
+
+func (c *WorkatoClient) DeleteRole(ctx context.Context, id int) (annotations.Annotations, error) {
+	pathString := fmt.Sprintf(RoleByIdPath, id)
+
+	return c.doRequest(ctx, http.MethodDelete, c.getPath(pathString), nil, nil)
 }
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// GetLookupTables returns one page of the lookup tables of the environment, pages start at 1.
+// https://docs.workato.com/workato-api/lookup-tables.html#list-lookup-tables
+func (c *WorkatoClient) GetLookupTables(ctx context.Context, pToken string) ([]LookupTable, string, annotations.Annotations, error) {
+	var response []LookupTable
+
+	uri, page, err := c.cappedPage(GetLookupTablesPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response, c.cappedNextToken(len(response), page), annos, nil
+}
+
+// GetAllLookupTables walks every page of lookup tables, they can't be filtered by folder.
+func (c *WorkatoClient) GetAllLookupTables(ctx context.Context) ([]LookupTable, error) {
+	all := make([]LookupTable, 0)
+
+	token := ""
+
+	for {
+		tables, nextToken, _, err := c.GetLookupTables(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, tables...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	return all, nil
+}
+
+// GetDataTables returns one page of the data tables of the environment, pages start at 1.
+// The data is wrapped without a total, so the last page is the first one that isn't full.
+// https://docs.workato.com/workato-api/data-tables.html#list-data-tables
+func (c *WorkatoClient) GetDataTables(ctx context.Context, pToken string) ([]DataTable, string, annotations.Annotations, error) {
+	var response CommonPagination[DataTable]
+
+	uri, page, err := c.cappedPage(GetDataTablesPath, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, uri, &response, nil)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return response.Data, c.cappedNextToken(len(response.Data), page), annos, nil
+}
+
+// GetAllDataTables walks every page of data tables, they can't be filtered by folder.
+func (c *WorkatoClient) GetAllDataTables(ctx context.Context) ([]DataTable, error) {
+	all := make([]DataTable, 0)
+
+	token := ""
+
+	for {
+		tables, nextToken, _, err := c.GetDataTables(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, tables...)
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	return all, nil
+}
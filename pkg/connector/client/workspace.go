@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+func (c *WorkatoClient) GetWorkspace(ctx context.Context) (*Workspace, annotations.Annotations, error) {
+	var response Workspace
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.getPath(GetWorkspacePath), &response, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &response, annos, nil
+}
+
+// DataCenter returns the data center the client points to, empty if the base url isn't a known data center.
+func (c *WorkatoClient) DataCenter() string {
+	for name, dataCenterUrl := range WorkatoDataCenters {
+		if dataCenterUrl == c.baseUrl.String() {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// FindDataCenter looks for the data center that accepts the API key.
+// Workato API keys only work in the data center they were created in, this is used to explain authentication failures.
+func (c *WorkatoClient) FindDataCenter(ctx context.Context) (string, bool) {
+	for name, dataCenterUrl := range WorkatoDataCenters {
+		if dataCenterUrl == c.baseUrl.String() {
+			continue
+		}
+
+		parsedUrl, err := url.Parse(dataCenterUrl)
+		if err != nil {
+			continue
+		}
+
+		other := *c
+		other.baseUrl = parsedUrl
+
+		_, _, err = other.GetWorkspace(ctx)
+		if err == nil {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -2,17 +2,40 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/cpagination"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 )
 
+const (
+	membersPage     = "members"
+	invitationsPage = "invitations"
+
+	// Pending invitations have no member id yet, they are identified by the invited email.
+	invitationIdPrefix = "invitation/"
+)
+
 type collaboratorBuilder struct {
-	client *client.WorkatoClient
+	client    *client.WorkatoClient
+	envs      *environments
+	roleCache *roleCache
+	// dryRun logs the invitations and removals instead of sending them.
+	dryRun bool
 }
 
 func (o *collaboratorBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -21,23 +44,80 @@ func (o *collaboratorBuilder) ResourceType(ctx context.Context) *v2.ResourceType
 
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
+// Members are listed first, then pending invitations.
 func (o *collaboratorBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	collaborators, err := o.client.GetCollaborators(ctx)
+	type Bag struct {
+		Page  string
+		Token string
+	}
+
+	bag, err := cpagination.GenBagFromToken[Bag](*pToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	rv := make([]*v2.Resource, len(collaborators))
+	if bag.Current() == nil {
+		bag.Push(Bag{Page: invitationsPage})
+		bag.Push(Bag{Page: membersPage})
+	}
+
+	state := bag.Pop()
+
+	var (
+		rv        []*v2.Resource
+		nextToken string
+		annos     annotations.Annotations
+	)
+
+	switch state.Page {
+	case membersPage:
+		var collaborators []client.Collaborator
+		collaborators, nextToken, annos, err = o.client.GetCollaborators(ctx, state.Token)
+		if err != nil {
+			return nil, "", annos, err
+		}
 
-	for i, collaborator := range collaborators {
-		us, err := collaboratorResource(&collaborator)
+		for _, collaborator := range collaborators {
+			us, err := collaboratorResource(&collaborator)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, us)
+		}
+	case invitationsPage:
+		var invitations []client.MemberInvitation
+		invitations, nextToken, annos, err = o.client.GetMemberInvitations(ctx, state.Token)
 		if err != nil {
-			return nil, "", nil, err
+			// Listing invitations isn't available on every plan, members are still synced.
+			if status.Code(err) == codes.NotFound || status.Code(err) == codes.PermissionDenied {
+				ctxzap.Extract(ctx).Warn("Unable to list pending invitations, skipping them", zap.Error(err))
+				return nil, "", annos, nil
+			}
+
+			return nil, "", annos, err
+		}
+
+		for _, invitation := range invitations {
+			us, err := invitationResource(&invitation)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, us)
 		}
-		rv[i] = us
+	default:
+		return nil, "", nil, fmt.Errorf("baton-workato: unknown collaborator page %s", state.Page)
+	}
+
+	if nextToken != "" {
+		bag.Push(Bag{Page: state.Page, Token: nextToken})
 	}
 
-	return rv, "", nil, nil
+	token, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, token, annos, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -50,9 +130,347 @@ func (o *collaboratorBuilder) Grants(ctx context.Context, resource *v2.Resource,
 	return nil, "", nil, nil
 }
 
-func newCollaboratorBuilder(client *client.WorkatoClient) *collaboratorBuilder {
+// accountCreationField is a key of the profile CreateAccount reads.
+type accountCreationField struct {
+	name        string
+	displayName string
+	description string
+	placeholder string
+	required    bool
+}
+
+// accountCreationSchema is the profile CreateAccount reads, it's published in the connector metadata.
+// The baton-sdk version in use has no ConnectorAccountCreationSchema yet, so it's published in the metadata profile.
+var accountCreationSchema = []accountCreationField{
+	{
+		name:        "email",
+		displayName: "Email",
+		description: "Email the invitation is sent to.",
+		placeholder: "jane@example.com",
+		required:    true,
+	},
+	{
+		name:        "role",
+		displayName: "Role",
+		description: "Base role or custom role name given in the environment.",
+		placeholder: "Analyst",
+		required:    true,
+	},
+	{
+		name:        "environment",
+		displayName: "Environment",
+		description: "Environment of the role, dev, test or prod. Defaults to the main environment.",
+		placeholder: "dev",
+	},
+	{
+		name:        "name",
+		displayName: "Name",
+		description: "Name of the collaborator. Defaults to the email.",
+		placeholder: "Jane Doe",
+	},
+}
+
+// accountCreationProfile describes accountCreationSchema as a profile value.
+func accountCreationProfile() map[string]interface{} {
+	rv := make(map[string]interface{}, len(accountCreationSchema))
+	for order, field := range accountCreationSchema {
+		rv[field.name] = map[string]interface{}{
+			"display_name": field.displayName,
+			"description":  field.description,
+			"placeholder":  field.placeholder,
+			"required":     field.required,
+			"order":        order,
+		}
+	}
+
+	return rv
+}
+
+// CreateAccount invites the collaborator, the profile needs an email and a role and can set the name and environment.
+// The role is a base role or a custom role name, the environment defaults to the main one.
+func (o *collaboratorBuilder) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	credentialOptions *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	invitation, err := o.invitationFromAccountInfo(ctx, accountInfo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Nothing is sent, so there is no invitation to return.
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info(
+			"baton-workato: dry run, collaborator was not invited",
+			zap.String("collaborator_email", invitation.Email),
+			zap.String("env_roles", envRolesDiff(nil, invitation.Roles)),
+		)
+		return nil, nil, nil, dryRunError("%s was not invited", invitation.Email)
+	}
+
+	annos, err := o.client.InviteCollaborator(ctx, invitation.Name, invitation.Email, invitation.Roles)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	us, err := invitationResource(invitation)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	return &v2.CreateAccountResponse_ActionRequiredResult{
+		Resource:              us,
+		Message:               fmt.Sprintf("Workato sent an invitation to %s, the collaborator is created once it's accepted", invitation.Email),
+		IsCreateAccountResult: true,
+	}, nil, annos, nil
+}
+
+// CreateAccountCapabilityDetails doesn't create credentials, Workato emails the invitation.
+func (o *collaboratorBuilder) CreateAccountCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+// Create isn't supported, collaborators are invited with CreateAccount.
+func (o *collaboratorBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, status.Error(codes.Unimplemented, "baton-workato: collaborators are invited with account provisioning")
+}
+
+// Delete removes the collaborator from the workspace.
+// The workspace owner is refused, it's the account /api/users/me describes and the one the API key acts as.
+// When the owner can't be identified nothing is removed.
+// The recipes the collaborator owned are logged as transferred when Workato no longer lists it as their owner, orphaned otherwise.
+// Connections aren't reported, Workato doesn't list who owns a connection.
+func (o *collaboratorBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if strings.HasPrefix(resourceId.Resource, invitationIdPrefix) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: %s is a pending invitation, it can only be cancelled in Workato",
+			strings.TrimPrefix(resourceId.Resource, invitationIdPrefix),
+		)
+	}
+
+	id, err := strconv.Atoi(resourceId.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner and the members are read fresh, a cached answer could remove the wrong account.
+	o.client.InvalidateCache(ctx)
+
+	workspace, annos, err := o.client.GetWorkspace(ctx)
+	if err != nil {
+		return annos, err
+	}
+
+	collaborators, err := o.client.GetAllCollaborators(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(collaborators, func(collaborator client.Collaborator) bool {
+		return collaborator.Id == id
+	})
+	if index < 0 {
+		l.Info("Collaborator is not a member of the workspace anymore", zap.Int("collaborator_id", id))
+		return nil, nil
+	}
+
+	collaborator := collaborators[index]
+
+	if workspace.Email == "" {
+		return nil, status.Error(
+			codes.FailedPrecondition,
+			"baton-workato: the workspace owner can't be identified, collaborators aren't removed without it",
+		)
+	}
+
+	if strings.EqualFold(collaborator.Email, workspace.Email) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: %s owns the workspace and the API key, it can't be removed",
+			collaborator.Email,
+		)
+	}
+
+	// The report is best effort, a key without the recipes scopes can still remove collaborators.
+	owned, ownedErr := o.ownedRecipes(ctx, id)
+	if ownedErr != nil {
+		l.Warn(
+			"Unable to list the recipes owned by the collaborator, their transfer isn't reported",
+			zap.Int("collaborator_id", id),
+			zap.Error(ownedErr),
+		)
+	}
+
+	if o.dryRun {
+		l.Info(
+			"baton-workato: dry run, collaborator was not removed",
+			zap.Int("collaborator_id", id),
+			zap.String("collaborator_email", collaborator.Email),
+			zap.Strings("owned_recipes", owned),
+		)
+		return nil, dryRunError("collaborator %d was not removed", id)
+	}
+
+	annos, err = o.client.DeleteCollaborator(ctx, id)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return annos, nil
+		}
+
+		return annos, err
+	}
+
+	fields := []zap.Field{
+		zap.Int("collaborator_id", id),
+		zap.String("collaborator_email", collaborator.Email),
+	}
+
+	if ownedErr == nil {
+		stillOwned, err := o.ownedRecipes(ctx, id)
+		if err != nil {
+			l.Warn(
+				"Unable to list the recipes owned by the collaborator after the removal, their transfer isn't reported",
+				zap.Int("collaborator_id", id),
+				zap.Error(err),
+			)
+		} else {
+			transferred, orphaned := recipeTransfers(owned, stillOwned)
+			fields = append(fields, zap.Strings("transferred_recipes", transferred), zap.Strings("orphaned_recipes", orphaned))
+		}
+	}
+
+	l.Info("Collaborator removed from the workspace", fields...)
+
+	return annos, nil
+}
+
+// ownedRecipes returns the resource ids of the recipes the collaborator owns in every synced environment.
+func (o *collaboratorBuilder) ownedRecipes(ctx context.Context, collaboratorId int) ([]string, error) {
+	rv := make([]string, 0)
+
+	for _, env := range o.envs.list() {
+		envClient, err := o.envs.client(env)
+		if err != nil {
+			return nil, err
+		}
+
+		parents, err := folderParents(ctx, envClient, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		folderIds := make([]int, 0, len(parents))
+		for folderId := range parents {
+			folderIds = append(folderIds, folderId)
+		}
+		slices.Sort(folderIds)
+
+		for _, folderId := range folderIds {
+			recipes, err := envClient.GetAllRecipes(ctx, folderId)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, recipe := range recipes {
+				if recipe.UserId == collaboratorId {
+					rv = append(rv, o.envs.resourceId(env, recipe.Id))
+				}
+			}
+		}
+	}
+
+	return rv, nil
+}
+
+// recipeTransfers splits the recipes a removed collaborator owned into the ones with a new owner and the ones left to it.
+func recipeTransfers(owned []string, stillOwned []string) ([]string, []string) {
+	transferred := make([]string, 0)
+	orphaned := make([]string, 0)
+
+	for _, recipeId := range owned {
+		if slices.Contains(stillOwned, recipeId) {
+			orphaned = append(orphaned, recipeId)
+		} else {
+			transferred = append(transferred, recipeId)
+		}
+	}
+
+	return transferred, orphaned
+}
+
+func (o *collaboratorBuilder) invitationFromAccountInfo(ctx context.Context, accountInfo *v2.AccountInfo) (*client.MemberInvitation, error) {
+	profile := accountInfo.GetProfile()
+
+	email, _ := resource.GetProfileStringValue(profile, "email")
+	if email == "" && len(accountInfo.GetEmails()) > 0 {
+		email = accountInfo.GetEmails()[0].GetAddress()
+	}
+	if email == "" && strings.Contains(accountInfo.GetLogin(), "@") {
+		email = accountInfo.GetLogin()
+	}
+	if email == "" {
+		return nil, status.Error(codes.InvalidArgument, "baton-workato: email is required to invite a collaborator")
+	}
+
+	name, _ := resource.GetProfileStringValue(profile, "name")
+	if name == "" {
+		firstName, _ := resource.GetProfileStringValue(profile, "first_name")
+		lastName, _ := resource.GetProfileStringValue(profile, "last_name")
+		name = strings.TrimSpace(firstName + " " + lastName)
+	}
+	if name == "" {
+		name = email
+	}
+
+	env := o.envs.main
+	if value, ok := resource.GetProfileStringValue(profile, "environment"); ok && value != "" {
+		parsedEnv, err := workato.EnvFromString(value)
+		if err != nil || !o.envs.has(value) {
+			return nil, status.Errorf(codes.InvalidArgument, "baton-workato: environment '%s' is not synced", value)
+		}
+		env = parsedEnv
+	}
+
+	roleName, _ := resource.GetProfileStringValue(profile, "role")
+	if roleName == "" {
+		return nil, status.Error(codes.InvalidArgument, "baton-workato: role is required to invite a collaborator")
+	}
+
+	if !workato.IsBaseRole(roleName) {
+		roles, err := o.roleCache.getRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		exists := slices.ContainsFunc(roles, func(role *client.Role) bool {
+			return role.Name == roleName
+		})
+		if !exists {
+			return nil, status.Errorf(codes.InvalidArgument, "baton-workato: role '%s' doesn't exist", roleName)
+		}
+	}
+
+	return &client.MemberInvitation{
+		Name:  name,
+		Email: email,
+		Roles: []client.SimpleRole{{EnvironmentType: env.String(), RoleName: roleName}},
+	}, nil
+}
+
+func newCollaboratorBuilder(client *client.WorkatoClient, envs *environments, roleCache *roleCache, dryRun bool) *collaboratorBuilder {
 	return &collaboratorBuilder{
-		client: client,
+		client:    client,
+		envs:      envs,
+		roleCache: roleCache,
+		dryRun:    dryRun,
 	}
 }
 
@@ -91,3 +509,45 @@ func collaboratorResource(collaborator *client.Collaborator) (*v2.Resource, erro
 
 	return ret, nil
 }
+
+func invitationResource(invitation *client.MemberInvitation) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"email":  invitation.Email,
+		"name":   invitation.Name,
+		"status": "pending",
+	}
+
+	for _, role := range invitation.Roles {
+		profile["role_"+role.EnvironmentType] = role.RoleName
+	}
+
+	traits := []resource.UserTraitOption{
+		resource.WithUserProfile(profile),
+		// The invitation can't be used to sign in until it's accepted.
+		resource.WithStatus(v2.UserTrait_Status_STATUS_DISABLED),
+		resource.WithEmail(invitation.Email, true),
+		resource.WithUserLogin(invitation.Email),
+		resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+	}
+
+	if !invitation.CreatedAt.IsZero() {
+		traits = append(traits, resource.WithCreatedAt(invitation.CreatedAt))
+	}
+
+	name := invitation.Name
+	if name == "" {
+		name = invitation.Email
+	}
+
+	ret, err := resource.NewUserResource(
+		name,
+		collaboratorResourceType,
+		invitationIdPrefix+strings.ToLower(invitation.Email),
+		traits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
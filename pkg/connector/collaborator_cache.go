@@ -2,13 +2,20 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/conductorone/baton-workato/pkg/connector/ucache"
 
 	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/store"
 	"github.com/conductorone/baton-workato/pkg/connector/workato"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type CompoundUser struct {
@@ -23,19 +30,46 @@ func (c *CompoundUser) Id() string {
 type collaboratorCache struct {
 	client          *client.WorkatoClient
 	privilegeToUser *ucache.HashSet[string, string, CompoundUser]
-	folderToUser    *ucache.HashSet[int, string, CompoundUser]
-	roleToUser      *ucache.HashSet[string, string, CompoundUser]
-	env             workato.Environment
+	// folderToUser is keyed by folder resource id and roleToUser by roleKey, both depend on the environment.
+	folderToUser *ucache.HashSet[string, string, CompoundUser]
+	roleToUser   *ucache.HashSet[string, string, CompoundUser]
+	envToUser    *ucache.HashSet[string, string, CompoundUser]
+	envs         *environments
+	// concurrency is how many collaborator privileges are fetched at the same time.
+	concurrency int
+	lazy        lazyCache
+	// store enables incremental syncs, the roles are used to know which collaborators changed.
+	store     *store.FileStore
+	roleCache *roleCache
 }
 
-func newCollaboratorCache(workatoClient *client.WorkatoClient, env workato.Environment) *collaboratorCache {
-	return &collaboratorCache{
+func newCollaboratorCache(
+	workatoClient *client.WorkatoClient,
+	envs *environments,
+	concurrency int,
+	roleCache *roleCache,
+	stateStore *store.FileStore,
+) *collaboratorCache {
+	cache := &collaboratorCache{
 		client:          workatoClient,
 		privilegeToUser: ucache.NewUCache[string, string, CompoundUser](),
-		folderToUser:    ucache.NewUCache[int, string, CompoundUser](),
+		folderToUser:    ucache.NewUCache[string, string, CompoundUser](),
 		roleToUser:      ucache.NewUCache[string, string, CompoundUser](),
-		env:             env,
+		envToUser:       ucache.NewUCache[string, string, CompoundUser](),
+		envs:            envs,
+		concurrency:     max(concurrency, 1),
+		store:           stateStore,
+		roleCache:       roleCache,
 	}
+
+	cache.lazy.build = cache.buildCache
+	cache.lazy.invalidate = workatoClient.InvalidateCache
+
+	return cache
+}
+
+func (p *collaboratorCache) beginSync() {
+	p.lazy.beginSync()
 }
 
 func (p *collaboratorCache) buildCache(ctx context.Context) error {
@@ -44,18 +78,58 @@ func (p *collaboratorCache) buildCache(ctx context.Context) error {
 	l.Info("Building cache for collaborators")
 
 	p.privilegeToUser = ucache.NewUCache[string, string, CompoundUser]()
-	p.folderToUser = ucache.NewUCache[int, string, CompoundUser]()
+	p.folderToUser = ucache.NewUCache[string, string, CompoundUser]()
 	p.roleToUser = ucache.NewUCache[string, string, CompoundUser]()
+	p.envToUser = ucache.NewUCache[string, string, CompoundUser]()
 
-	collaborators, err := p.client.GetCollaborators(ctx)
+	syncedAt := time.Now()
+
+	// Roles and folders are read first, a change made while the cache is built is picked up by the next incremental sync.
+	var stamps *accessStamps
+	if p.store != nil {
+		var err error
+		stamps, err = p.readAccessStamps(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	collaborators, err := p.client.GetAllCollaborators(ctx)
 	if err != nil {
 		return err
 	}
 
+	reused := p.reusablePrivileges(ctx, collaborators, stamps)
+
+	toFetch := make([]client.Collaborator, 0, len(collaborators))
 	for _, collaborator := range collaborators {
-		collaboratorRoles, err := p.client.GetCollaboratorPrivileges(ctx, collaborator.Id)
-		if err != nil {
-			return err
+		if _, ok := reused[collaborator.Id]; !ok {
+			toFetch = append(toFetch, collaborator)
+		}
+	}
+
+	fetched, err := p.fetchPrivileges(ctx, toFetch)
+	if err != nil {
+		return err
+	}
+
+	for i, collaborator := range toFetch {
+		if fetched[i] != nil {
+			reused[collaborator.Id] = fetched[i]
+		}
+	}
+
+	allPrivileges := make([][]*client.CollaboratorPrivilege, len(collaborators))
+	for i, collaborator := range collaborators {
+		allPrivileges[i] = reused[collaborator.Id]
+	}
+
+	p.saveSnapshot(ctx, syncedAt, stamps, collaborators, allPrivileges)
+
+	for i, collaborator := range collaborators {
+		collaboratorRoles := allPrivileges[i]
+		if collaboratorRoles == nil {
+			continue
 		}
 
 		compoundUser := &CompoundUser{
@@ -64,7 +138,7 @@ func (p *collaboratorCache) buildCache(ctx context.Context) error {
 		}
 
 		for _, collaboratorRole := range collaboratorRoles {
-			if collaboratorRole.EnvironmentType != p.env.String() {
+			if !p.envs.has(collaboratorRole.EnvironmentType) {
 				continue
 			}
 
@@ -79,17 +153,19 @@ func (p *collaboratorCache) buildCache(ctx context.Context) error {
 
 			// Build for folders
 			for _, folderId := range collaboratorRole.FolderIDs {
-				p.folderToUser.Set(folderId, compoundUser.Id(), compoundUser)
+				folderKey := p.envs.resourceId(workato.Environment(collaboratorRole.EnvironmentType), folderId)
+				p.folderToUser.Set(folderKey, compoundUser.Id(), compoundUser)
 			}
 		}
 
 		// Build for roles
 		for _, role := range collaborator.Roles {
-			if role.EnvironmentType != p.env.String() {
+			if !p.envs.has(role.EnvironmentType) {
 				continue
 			}
 
-			p.roleToUser.Set(role.RoleName, compoundUser.Id(), compoundUser)
+			p.roleToUser.Set(roleKey(role.EnvironmentType, role.RoleName), compoundUser.Id(), compoundUser)
+			p.envToUser.Set(role.EnvironmentType, compoundUser.Id(), compoundUser)
 		}
 	}
 
@@ -98,13 +174,91 @@ func (p *collaboratorCache) buildCache(ctx context.Context) error {
 	return nil
 }
 
+// fetchPrivileges gets the privileges of every collaborator, at most p.concurrency requests run at the same time.
+// The client rate limiter and retries are shared by every worker.
+// A collaborator that fails is logged and left as nil, the cache is built with partial results and a warning.
+// It only fails when no collaborator could be read, a partial result would then be an empty one.
+func (p *collaboratorCache) fetchPrivileges(ctx context.Context, collaborators []client.Collaborator) ([][]*client.CollaboratorPrivilege, error) {
+	l := ctxzap.Extract(ctx)
+
+	results := make([][]*client.CollaboratorPrivilege, len(collaborators))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		lastErr error
+		failed  int
+	)
+
+	workers := make(chan struct{}, p.concurrency)
+
+	for i, collaborator := range collaborators {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		case workers <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			privileges, _, err := p.client.GetCollaboratorPrivileges(ctx, collaborator.Id)
+			if err != nil {
+				// The member was removed since the members were listed, there is nothing left to grant.
+				if status.Code(err) == codes.NotFound {
+					l.Info("Collaborator was removed during the sync, skipping collaborator", zap.Int("collaborator_id", collaborator.Id))
+					return
+				}
+
+				l.Warn(
+					"Unable to get collaborator privileges",
+					zap.Int("collaborator_id", collaborator.Id),
+					zap.Error(err),
+				)
+
+				mu.Lock()
+				failed++
+				lastErr = err
+				mu.Unlock()
+
+				return
+			}
+
+			results[i] = privileges
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if failed > 0 {
+		if failed == len(collaborators) {
+			return nil, fmt.Errorf("baton-workato: unable to get privileges of any collaborator: %w", lastErr)
+		}
+
+		l.Warn(
+			"Collaborator cache built with partial results",
+			zap.Int("failed_collaborators", failed),
+			zap.Int("total_collaborators", len(collaborators)),
+		)
+	}
+
+	return results, nil
+}
+
 // GetAllFoldersRecur is a recursive function that gets all folders in a Workato instance.
 func (p *collaboratorCache) GetAllFoldersRecur(ctx context.Context, parentId *int, pToken string) ([]client.Folder, error) {
 	l := ctxzap.Extract(ctx)
 
 	l.Info("Building cache for folders")
 
-	folders, nextToken, err := p.client.GetFolders(ctx, parentId, pToken)
+	folders, nextToken, _, err := p.client.GetFolders(ctx, parentId, pToken)
 	if err != nil {
 		return nil, err
 	}
@@ -134,14 +288,36 @@ func (p *collaboratorCache) GetAllFoldersRecur(ctx context.Context, parentId *in
 	return response, nil
 }
 
-func (p *collaboratorCache) getUsersByPrivilege(privilegeKey string) []*CompoundUser {
-	return p.privilegeToUser.GetAll(privilegeKey)
+func (p *collaboratorCache) getUsersByPrivilege(ctx context.Context, privilegeKey string) ([]*CompoundUser, error) {
+	var rv []*CompoundUser
+	err := p.lazy.read(ctx, func() {
+		rv = p.privilegeToUser.GetAll(privilegeKey)
+	})
+	return rv, err
+}
+
+// getUsersByFolder takes the folder resource id.
+func (p *collaboratorCache) getUsersByFolder(ctx context.Context, folderResourceId string) ([]*CompoundUser, error) {
+	var rv []*CompoundUser
+	err := p.lazy.read(ctx, func() {
+		rv = p.folderToUser.GetAll(folderResourceId)
+	})
+	return rv, err
 }
 
-func (p *collaboratorCache) getUsersByFolder(folderId int) []*CompoundUser {
-	return p.folderToUser.GetAll(folderId)
+func (p *collaboratorCache) getUsersByRole(ctx context.Context, env workato.Environment, roleName string) ([]*CompoundUser, error) {
+	var rv []*CompoundUser
+	err := p.lazy.read(ctx, func() {
+		rv = p.roleToUser.GetAll(roleKey(env.String(), roleName))
+	})
+	return rv, err
 }
 
-func (p *collaboratorCache) getUsersByRole(roleName string) []*CompoundUser {
-	return p.roleToUser.GetAll(roleName)
+// getUsersByEnvironment returns the collaborators holding any role in the environment.
+func (p *collaboratorCache) getUsersByEnvironment(ctx context.Context, env workato.Environment) ([]*CompoundUser, error) {
+	var rv []*CompoundUser
+	err := p.lazy.read(ctx, func() {
+		rv = p.envToUser.GetAll(env.String())
+	})
+	return rv, err
 }
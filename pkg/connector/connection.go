@@ -0,0 +1,204 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	connectionReadEntitlement   = "read"
+	connectionEditEntitlement   = "edit"
+	connectionDeleteEntitlement = "delete"
+)
+
+// connectionEntitlements maps the connection entitlements to the Connections privilege giving them.
+// Reading a connection is enough to use it in the recipes of its folder.
+var connectionEntitlements = []privilegeEntitlement{
+	{slug: connectionReadEntitlement, privilege: "read"},
+	{slug: connectionEditEntitlement, privilege: "update"},
+	{slug: connectionDeleteEntitlement, privilege: "delete"},
+}
+
+const connectionsPrivilegeGroup = "Connections"
+
+type connectionBuilder struct {
+	envs      *environments
+	cache     *collaboratorCache
+	roleCache *roleCache
+	// onPrem links the connections to the on-prem group they reach their system through.
+	onPrem bool
+}
+
+func (o *connectionBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return connectionResourceType
+}
+
+// List returns the connections directly in a folder, they are read with the client of the environment the folder belongs to.
+func (o *connectionBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	if parentResourceID.ResourceType != folderResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	env, folderId, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	connections, annos, err := envClient.GetConnections(ctx, folderId)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(connections))
+
+	for _, connection := range connections {
+		us, err := connectionResource(&connection, o.envs.resourceId(env, connection.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, "", annos, nil
+}
+
+func (o *connectionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return newPrivilegeEntitlements(resource, connectionsPrivilegeGroup, connectionEntitlements, collaboratorResourceType, roleResourceType), "", nil, nil
+}
+
+// Grants gives a connection entitlement to the roles and collaborators having the Connections privilege and access to the folder of the connection.
+func (o *connectionBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, _, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := folderPrivilegeGrants(ctx, o.envs, o.cache, o.roleCache, resource, env, connectionsPrivilegeGroup, connectionEntitlements)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if o.onPrem {
+		onPremGrant, err := o.onPremGroupGrant(resource, env)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if onPremGrant != nil {
+			rv = append(rv, onPremGrant)
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+// onPremGroupGrant links the connection to its on-prem group, nil when the connection doesn't go through an agent.
+// Workato only links them from the connection, so the grant of the group entitlement is returned with the connection.
+// It's expandable, whoever can use the connection reaches the on-prem system.
+func (o *connectionBuilder) onPremGroupGrant(resource *v2.Resource, env workato.Environment) (*v2.Grant, error) {
+	appTrait, err := rs.GetAppTrait(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	groupId, ok := rs.GetProfileInt64Value(appTrait.GetProfile(), "on_prem_group_id")
+	if !ok {
+		return nil, nil
+	}
+
+	groupResourceId, err := rs.NewResourceID(onPremGroupResourceType, o.envs.resourceId(env, int(groupId)))
+	if err != nil {
+		return nil, err
+	}
+
+	entitlementIds := make([]string, 0, len(connectionEntitlements))
+	for _, connectionEntitlement := range connectionEntitlements {
+		entitlementIds = append(entitlementIds, entitlement.NewEntitlementID(resource, connectionEntitlement.slug))
+	}
+
+	return grant.NewGrant(
+		&v2.Resource{Id: groupResourceId},
+		onPremConnectionEntitlement,
+		resource.Id,
+		grant.WithAnnotation(
+			&v2.GrantExpandable{
+				EntitlementIds: entitlementIds,
+				Shallow:        true,
+			},
+			&v2.GrantImmutable{},
+		),
+	), nil
+}
+
+func newConnectionBuilder(envs *environments, cache *collaboratorCache, roleCache *roleCache, onPrem bool) *connectionBuilder {
+	return &connectionBuilder{
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+		onPrem:    onPrem,
+	}
+}
+
+func connectionResource(connection *client.Connection, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":                     connection.Id,
+		"name":                   connection.Name,
+		"description":            connection.Description,
+		"application":            connection.Application,
+		"provider":               connection.Provider,
+		"folder_id":              connection.FolderId,
+		"external_id":            connection.ExternalId,
+		"authorization_status":   connection.AuthorizationStatus,
+		"authorization_error":    connection.AuthorizationError,
+		"authorized_at":          formatOptionalTime(connection.AuthorizedAt),
+		"connection_lost_at":     formatOptionalTime(connection.ConnectionLostAt),
+		"connection_lost_reason": connection.ConnectionLostReason,
+		"created_at":             connection.CreatedAt.String(),
+		"updated_at":             connection.UpdatedAt.String(),
+	}
+
+	if connection.OnPremGroupId != nil {
+		profile["on_prem_group_id"] = *connection.OnPremGroupId
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		connection.Name,
+		connectionResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -2,31 +2,221 @@ package connector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/conductorone/baton-workato/pkg/connector/workato"
 
 	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/store"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+const defaultPrivilegesConcurrency = 4
+
 type Connector struct {
-	client *client.WorkatoClient
-	env    workato.Environment
+	client                *client.WorkatoClient
+	env                   workato.Environment
+	envClients            map[workato.Environment]*client.WorkatoClient
+	envs                  *environments
+	privilegesConcurrency int
+	fallbackRole          string
+	dryRun                bool
+	elevatedRoles         []string
+	elevationDuration     time.Duration
+	store                 *store.FileStore
+	incremental           bool
+	apiPlatform           bool
+	onPrem                bool
+	recipes               bool
+	connections           bool
+	tables                bool
+	// Caches are shared by every builder, so a sync downloads collaborator privileges and roles once.
+	collaboratorCache *collaboratorCache
+	roleCache         *roleCache
+	// collaboratorLocks serializes the role updates of a collaborator across builders,
+	// roleLocks the folder and privilege updates of a custom role.
+	collaboratorLocks *keyedLocks
+	roleLocks         *keyedLocks
+	elevations        *elevations
+	accessProfiles    *accessProfileCache
+	lookupTables      *tableCache[client.LookupTable]
+	dataTables        *tableCache[client.DataTable]
+}
+
+type Option func(c *Connector)
+
+// WithPrivilegesConcurrency sets how many collaborator privileges are fetched at the same time.
+func WithPrivilegesConcurrency(concurrency int) Option {
+	return func(c *Connector) {
+		c.privilegesConcurrency = concurrency
+	}
+}
+
+// WithRevokeFallbackRole sets the role given to a collaborator when its last role is revoked.
+func WithRevokeFallbackRole(roleName string) Option {
+	return func(c *Connector) {
+		c.fallbackRole = roleName
+	}
+}
+
+// WithDryRun logs the changes of grants, revokes, invitations, creations and deletions instead of applying them.
+// Each of those calls then fails with FailedPrecondition, so a change is never reported as applied when nothing was sent.
+// Give the clients client.WithDryRun as well, a write missed here is then refused instead of sent.
+func WithDryRun(dryRun bool) Option {
+	return func(c *Connector) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithElevation makes the roles temporary, granting one of them saves the role it replaces in the environment.
+// The previous role is restored when the elevated role is revoked or, with a positive duration, when it expires.
+// The elevated role is removed instead when the environment had no role. It needs a store.
+func WithElevation(roles []string, duration time.Duration) Option {
+	return func(c *Connector) {
+		c.elevatedRoles = roles
+		c.elevationDuration = duration
+	}
+}
+
+// WithApiPlatform syncs the API Platform collections, clients and access profiles, the API key needs the API Platform scopes.
+func WithApiPlatform(apiPlatform bool) Option {
+	return func(c *Connector) {
+		c.apiPlatform = apiPlatform
+	}
+}
+
+// WithOnPrem syncs the on-prem groups and agents, the API key needs the on-prem scopes.
+func WithOnPrem(onPrem bool) Option {
+	return func(c *Connector) {
+		c.onPrem = onPrem
+	}
+}
+
+// WithRecipes syncs the recipes of the folders, the API key needs the recipes scopes.
+func WithRecipes(recipes bool) Option {
+	return func(c *Connector) {
+		c.recipes = recipes
+	}
+}
+
+// WithConnections syncs the connections of the folders, the API key needs the connections scopes.
+// The on-prem groups only list the connections reaching their systems with it.
+func WithConnections(connections bool) Option {
+	return func(c *Connector) {
+		c.connections = connections
+	}
+}
+
+// WithTables syncs the lookup tables and data tables of the folders, the API key needs the scopes of both.
+func WithTables(tables bool) Option {
+	return func(c *Connector) {
+		c.tables = tables
+	}
+}
+
+// WithEnvironmentClients syncs more environments, each with the client using its own API key.
+// The environment given to New stays the main one, members and roles are read with its client.
+func WithEnvironmentClients(clients map[workato.Environment]*client.WorkatoClient) Option {
+	return func(c *Connector) {
+		c.envClients = clients
+	}
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newCollaboratorBuilder(d.client),
-		newPrivilegeBuilder(d.client, d.env),
-		newRoleBuilder(d.client, d.env),
-		newFolderBuilder(d.client, d.env),
-		newProjectBuilder(d.client),
+	syncers := []connectorbuilder.ResourceSyncer{
+		newCollaboratorBuilder(d.client, d.envs, d.roleCache, d.dryRun),
+		newPrivilegeBuilder(d.client, d.collaboratorCache),
+		newRoleBuilder(d.client, d.envs, d.collaboratorCache, d.roleCache, d.fallbackRole, d.dryRun, d.collaboratorLocks, d.roleLocks, d.elevations),
+		newFolderBuilder(d.envs, d.collaboratorCache, d.roleCache, d.dryRun, d.roleLocks, d.folderChildren()),
+		newProjectBuilder(d.envs),
 	}
+
+	if d.recipes {
+		syncers = append(syncers, newRecipeBuilder(d.envs, d.collaboratorCache))
+	}
+
+	if d.connections {
+		syncers = append(syncers, newConnectionBuilder(d.envs, d.collaboratorCache, d.roleCache, d.onPrem))
+	}
+
+	if d.tables {
+		syncers = append(
+			syncers,
+			newLookupTableBuilder(d.envs, d.collaboratorCache, d.roleCache, d.lookupTables),
+			newDataTableBuilder(d.envs, d.collaboratorCache, d.roleCache, d.dataTables),
+		)
+	}
+
+	if d.apiPlatform {
+		syncers = append(
+			syncers,
+			newApiCollectionBuilder(d.envs, d.accessProfiles, d.dryRun),
+			newApiClientBuilder(d.envs),
+			newApiAccessProfileBuilder(d.envs, d.accessProfiles, d.dryRun),
+		)
+	}
+
+	if d.onPrem {
+		syncers = append(
+			syncers,
+			newOnPremGroupBuilder(d.envs, d.collaboratorCache, d.roleCache),
+			newOnPremAgentBuilder(d.envs, d.collaboratorCache, d.roleCache),
+		)
+	}
+
+	if d.envs.multi {
+		syncers = append(syncers, newEnvironmentBuilder(d.envs, d.collaboratorCache, d.environmentChildren()))
+	}
+
+	return syncers
+}
+
+// environmentChildren are the resource types listed under their environment in multi environment mode.
+func (d *Connector) environmentChildren() []*v2.ResourceType {
+	children := []*v2.ResourceType{projectResourceType}
+
+	if d.apiPlatform {
+		children = append(children, apiCollectionResourceType, apiClientResourceType, apiAccessProfileResourceType)
+	}
+
+	if d.onPrem {
+		children = append(children, onPremGroupResourceType)
+	}
+
+	return children
+}
+
+// folderChildren are the resource types listed under each folder.
+func (d *Connector) folderChildren() []*v2.ResourceType {
+	children := []*v2.ResourceType{folderResourceType}
+
+	if d.recipes {
+		children = append(children, recipeResourceType)
+	}
+
+	if d.connections {
+		children = append(children, connectionResourceType)
+	}
+
+	if d.tables {
+		children = append(children, lookupTableResourceType, dataTableResourceType)
+	}
+
+	return children
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -36,23 +226,507 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 }
 
 // Metadata returns metadata about the connector.
+// The profile has the account_creation_schema, the keys of the profile CreateAccount reads.
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"account_creation_schema": accountCreationProfile(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Workato connecotr",
 		Description: "Connector syncing Workato to Baton.",
+		Profile:     profile,
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	workspace, _, err := d.client.GetWorkspace(ctx)
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			return nil, d.dataCenterError(ctx, err)
+		}
+
+		return nil, fmt.Errorf("baton-workato: unable to get workspace details: %w", err)
+	}
+
+	l.Info("Validating workspace", zap.Int("workspace_id", workspace.Id), zap.String("workspace_name", workspace.Name))
+
+	var (
+		collaborators []client.Collaborator
+		projects      []client.Project
+	)
+
+	type scopeProbe struct {
+		scope string
+		probe func() error
+	}
+
+	probes := []scopeProbe{
+		{
+			scope: "Workspace collaborators: List collaborators",
+			probe: func() error {
+				var err error
+				collaborators, _, _, err = d.client.GetCollaborators(ctx, "")
+				return err
+			},
+		},
+		{
+			scope: "Workspace collaborators: Get collaborator privileges",
+			probe: func() error {
+				// Without collaborators there is nothing to probe, the sync won't ask for privileges either.
+				if len(collaborators) == 0 {
+					return nil
+				}
+
+				_, _, err := d.client.GetCollaboratorPrivileges(ctx, collaborators[0].Id)
+				return err
+			},
+		},
+		{
+			scope: "Collaborator roles: List roles",
+			probe: func() error {
+				_, _, _, err := d.client.GetRoles(ctx, "")
+				return err
+			},
+		},
+		{
+			scope: "Projects: List folders",
+			probe: func() error {
+				_, _, _, err := d.client.GetFolders(ctx, nil, "")
+				return err
+			},
+		},
+		{
+			scope: "Projects: List projects",
+			probe: func() error {
+				var err error
+				projects, _, _, err = d.client.GetProjects(ctx, "")
+				return err
+			},
+		},
+	}
+
+	if d.recipes {
+		probes = append(probes, scopeProbe{
+			scope: "Projects: List recipes",
+			probe: func() error {
+				// Recipes are listed by folder, without projects the sync won't ask for them either.
+				if len(projects) == 0 {
+					return nil
+				}
+
+				_, _, _, err := d.client.GetRecipes(ctx, projects[0].FolderId, "")
+				return err
+			},
+		})
+	}
+
+	if d.connections {
+		probes = append(probes, scopeProbe{
+			scope: "Projects: List connections",
+			probe: func() error {
+				// Connections are listed by folder, without projects the sync won't ask for them either.
+				if len(projects) == 0 {
+					return nil
+				}
+
+				_, _, err := d.client.GetConnections(ctx, projects[0].FolderId)
+				return err
+			},
+		})
+	}
+
+	if d.tables {
+		probes = append(
+			probes,
+			scopeProbe{
+				scope: "Tools: List lookup tables",
+				probe: func() error {
+					_, _, _, err := d.client.GetLookupTables(ctx, "")
+					return err
+				},
+			},
+			scopeProbe{
+				scope: "Tools: List data tables",
+				probe: func() error {
+					_, _, _, err := d.client.GetDataTables(ctx, "")
+					return err
+				},
+			},
+		)
+	}
+
+	if d.apiPlatform {
+		probes = append(
+			probes,
+			scopeProbe{
+				scope: "API Platform: List API collections",
+				probe: func() error {
+					_, _, _, err := d.client.GetApiCollections(ctx, "")
+					return err
+				},
+			},
+			scopeProbe{
+				scope: "API Platform: List API clients",
+				probe: func() error {
+					_, _, _, err := d.client.GetApiClients(ctx, "")
+					return err
+				},
+			},
+			scopeProbe{
+				scope: "API Platform: List API access profiles",
+				probe: func() error {
+					_, _, _, err := d.client.GetApiAccessProfiles(ctx, "")
+					return err
+				},
+			},
+		)
+	}
+
+	if d.onPrem {
+		probes = append(probes, scopeProbe{
+			scope: "On-prem groups & agents: List on-prem groups",
+			probe: func() error {
+				_, _, _, err := d.client.GetOnPremGroups(ctx, "")
+				return err
+			},
+		})
+	}
+
+	var missingScopes []string
+
+	for _, p := range probes {
+		err := p.probe()
+		if err == nil {
+			continue
+		}
+
+		if !isMissingScope(err) {
+			return nil, err
+		}
+
+		missingScopes = append(missingScopes, p.scope)
+	}
+
+	if len(missingScopes) > 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "baton-workato: API key is missing scopes: %s", strings.Join(missingScopes, ", "))
+	}
+
+	err = d.validateEnvironment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, env := range d.envs.list() {
+		if env == d.env {
+			continue
+		}
+
+		err = d.validateEnvironmentClient(ctx, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = d.validateRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
+// validateRoles checks that the revoke fallback role and the elevated roles are base roles or custom roles of the workspace.
+func (d *Connector) validateRoles(ctx context.Context) error {
+	type roleSetting struct {
+		setting string
+		name    string
+	}
+
+	var settings []roleSetting
+	if d.fallbackRole != "" {
+		settings = append(settings, roleSetting{setting: "revoke fallback role", name: d.fallbackRole})
+	}
+
+	for _, name := range d.elevatedRoles {
+		settings = append(settings, roleSetting{setting: "elevated role", name: name})
+	}
+
+	var roles []client.Role
+
+	for _, setting := range settings {
+		if workato.IsBaseRole(setting.name) {
+			continue
+		}
+
+		if roles == nil {
+			var err error
+			roles, err = d.client.GetAllRoles(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		exists := slices.ContainsFunc(roles, func(role client.Role) bool {
+			return role.Name == setting.name
+		})
+
+		if !exists {
+			return status.Errorf(codes.InvalidArgument, "baton-workato: %s '%s' doesn't exist", setting.setting, setting.name)
+		}
+	}
+
+	return nil
+}
+
+// validateEnvironmentClient checks the API key of an extra environment, it's only used to list folders and projects.
+func (d *Connector) validateEnvironmentClient(ctx context.Context, env workato.Environment) error {
+	envClient, err := d.envs.client(env)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = envClient.GetWorkspace(ctx)
+	if err != nil {
+		return fmt.Errorf("baton-workato: API key of environment '%s' was rejected: %w", env, err)
+	}
+
+	var missingScopes []string
+
+	_, _, _, err = envClient.GetFolders(ctx, nil, "")
+	if err != nil {
+		if !isMissingScope(err) {
+			return err
+		}
+		missingScopes = append(missingScopes, "Projects: List folders")
+	}
+
+	projects, _, _, err := envClient.GetProjects(ctx, "")
+	if err != nil {
+		if !isMissingScope(err) {
+			return err
+		}
+		missingScopes = append(missingScopes, "Projects: List projects")
+	}
+
+	if d.recipes && len(projects) > 0 {
+		_, _, _, err = envClient.GetRecipes(ctx, projects[0].FolderId, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "Projects: List recipes")
+		}
+	}
+
+	if d.connections && len(projects) > 0 {
+		_, _, err = envClient.GetConnections(ctx, projects[0].FolderId)
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "Projects: List connections")
+		}
+	}
+
+	if d.tables {
+		_, _, _, err = envClient.GetLookupTables(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "Tools: List lookup tables")
+		}
+
+		_, _, _, err = envClient.GetDataTables(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "Tools: List data tables")
+		}
+	}
+
+	if d.apiPlatform {
+		_, _, _, err = envClient.GetApiCollections(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "API Platform: List API collections")
+		}
+
+		_, _, _, err = envClient.GetApiClients(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "API Platform: List API clients")
+		}
+
+		_, _, _, err = envClient.GetApiAccessProfiles(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "API Platform: List API access profiles")
+		}
+	}
+
+	if d.onPrem {
+		_, _, _, err = envClient.GetOnPremGroups(ctx, "")
+		if err != nil {
+			if !isMissingScope(err) {
+				return err
+			}
+			missingScopes = append(missingScopes, "On-prem groups & agents: List on-prem groups")
+		}
+	}
+
+	if len(missingScopes) > 0 {
+		return status.Errorf(
+			codes.PermissionDenied,
+			"baton-workato: API key of environment '%s' is missing scopes: %s",
+			env,
+			strings.Join(missingScopes, ", "),
+		)
+	}
+
+	return nil
+}
+
+func isMissingScope(err error) bool {
+	code := status.Code(err)
+	return code == codes.PermissionDenied || code == codes.Unauthenticated
+}
+
+// dryRunError is returned by every provisioning call in dry run mode once the change is logged,
+// so a grant, revoke, creation or deletion is never reported as done when nothing was applied.
+func dryRunError(format string, args ...any) error {
+	return status.Errorf(codes.FailedPrecondition, "baton-workato: dry run, "+format, args...)
+}
+
+// dataCenterError explains an authentication failure, API keys only work in the data center they were created in.
+func (d *Connector) dataCenterError(ctx context.Context, err error) error {
+	// A custom base url isn't a data center, nothing else to try.
+	if d.client.DataCenter() == "" {
+		return fmt.Errorf("baton-workato: API key was rejected by %s: %w", d.client.BaseUrl(), err)
+	}
+
+	dataCenter, ok := d.client.FindDataCenter(ctx)
+	if !ok {
+		return fmt.Errorf("baton-workato: API key was rejected: %w", err)
+	}
+
+	return status.Errorf(
+		codes.InvalidArgument,
+		"baton-workato: API key belongs to the '%s' data center but the connector is configured for '%s'",
+		dataCenter,
+		d.client.DataCenter(),
+	)
+}
+
+// validateEnvironment checks that the workspace has every synced environment.
+// Workato has no endpoint listing environments, every environment has at least one collaborator with a role in it.
+func (d *Connector) validateEnvironment(ctx context.Context) error {
+	missing := make(map[string]bool)
+	for _, env := range d.envs.list() {
+		missing[env.String()] = true
+	}
+
+	token := ""
+
+	for {
+		collaborators, nextToken, _, err := d.client.GetCollaborators(ctx, token)
+		if err != nil {
+			return err
+		}
+
+		for _, collaborator := range collaborators {
+			for _, role := range collaborator.Roles {
+				delete(missing, role.EnvironmentType)
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+
+		if nextToken == "" {
+			break
+		}
+
+		token = nextToken
+	}
+
+	envs := make([]string, 0, len(missing))
+	for _, env := range d.envs.list() {
+		if missing[env.String()] {
+			envs = append(envs, env.String())
+		}
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "baton-workato: environment '%s' was not found in the workspace", strings.Join(envs, "', '"))
+}
+
+// WithStore keeps connector state between runs.
+func WithStore(stateStore *store.FileStore) Option {
+	return func(c *Connector) {
+		c.store = stateStore
+	}
+}
+
+// WithIncrementalSync reuses collaborator privileges that didn't change since the previous sync, it needs a store.
+func WithIncrementalSync(incremental bool) Option {
+	return func(c *Connector) {
+		c.incremental = incremental
+	}
+}
+
 // New returns a new instance of the connector.
-func New(ctx context.Context, workatoClient *client.WorkatoClient, env workato.Environment) (*Connector, error) {
-	return &Connector{
-		client: workatoClient,
-		env:    env,
-	}, nil
+func New(ctx context.Context, workatoClient *client.WorkatoClient, env workato.Environment, opts ...Option) (*Connector, error) {
+	c := &Connector{
+		client:                workatoClient,
+		env:                   env,
+		privilegesConcurrency: defaultPrivilegesConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.incremental && c.store == nil {
+		return nil, errors.New("baton-workato: incremental sync needs a state store")
+	}
+
+	if len(c.elevatedRoles) > 0 {
+		if c.store == nil {
+			return nil, errors.New("baton-workato: elevated roles need a state store")
+		}
+
+		c.elevations = newElevations(c.store, c.elevatedRoles, c.elevationDuration)
+	}
+
+	var incrementalStore *store.FileStore
+	if c.incremental {
+		incrementalStore = c.store
+	}
+
+	c.envs = newEnvironments(env, workatoClient, c.envClients)
+	c.roleCache = newRoleCache(workatoClient)
+	c.collaboratorLocks = newKeyedLocks()
+	c.roleLocks = newKeyedLocks()
+	c.collaboratorCache = newCollaboratorCache(workatoClient, c.envs, c.privilegesConcurrency, c.roleCache, incrementalStore)
+	c.accessProfiles = newAccessProfileCache(c.envs)
+	c.lookupTables = newLookupTableCache(c.envs)
+	c.dataTables = newDataTableCache(c.envs)
+
+	return c, nil
 }
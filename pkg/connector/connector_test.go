@@ -0,0 +1,1884 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/store"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"github.com/conductorone/baton-workato/pkg/connector/workatotest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestServer returns a workspace with:
+//   - alice: Admin in dev, access to folders 100 and 101
+//   - bob: custom role "Builders" in dev, access to folder 100
+//   - carol: Analyst in prod only
+func newTestServer(t *testing.T) *workatotest.Server {
+	server := workatotest.NewServer(t)
+
+	server.AddRole(client.Role{
+		Id:        10,
+		Name:      "Builders",
+		FolderIDs: []int{100},
+		Privileges: map[string][]string{
+			"Recipes": {"read", "run"},
+			"Folders": {"read"},
+		},
+	})
+
+	server.AddProject(client.Project{Id: 1, Name: "Sales", FolderId: 100})
+	server.AddFolder(client.Folder{Id: 100, Name: "Sales"})
+	server.AddFolder(client.Folder{Id: 101, Name: "Leads", ParentId: 100})
+
+	salesforceConnection := 500
+	server.AddRecipe(client.Recipe{
+		Id:         1000,
+		Name:       "Sync opportunities",
+		FolderId:   100,
+		Running:    true,
+		AuthorName: "alice",
+		Config: []client.RecipeConnection{
+			{Keyword: "application", Name: "salesforce", Provider: "salesforce", AccountId: &salesforceConnection},
+			{Keyword: "application", Name: "logger", Provider: "logger"},
+		},
+	})
+	server.AddRecipe(client.Recipe{Id: 1001, Name: "Score leads", FolderId: 101})
+
+	server.AddCollaborator(
+		client.Collaborator{Id: 1, Name: "alice", Email: "alice@example.com"},
+		&client.CollaboratorPrivilege{
+			EnvironmentType: "dev",
+			Name:            "Admin",
+			Privileges:      map[string][]string{"Recipes": {"read", "update"}},
+			FolderIDs:       []int{100, 101},
+		},
+	)
+	server.AddCollaborator(
+		client.Collaborator{Id: 2, Name: "bob", Email: "bob@example.com"},
+		&client.CollaboratorPrivilege{
+			EnvironmentType: "dev",
+			Name:            "Builders",
+			Privileges: map[string][]string{
+				"Recipes": {"read", "run"},
+				"Folders": {"read"},
+			},
+			FolderIDs: []int{100},
+		},
+	)
+	server.AddCollaborator(
+		client.Collaborator{Id: 3, Name: "carol", Email: "carol@example.com"},
+		&client.CollaboratorPrivilege{
+			EnvironmentType: "prod",
+			Name:            "Analyst",
+			Privileges:      map[string][]string{"Recipes": {"read"}},
+			FolderIDs:       []int{101},
+		},
+	)
+
+	return server
+}
+
+func newTestConnector(t *testing.T, server *workatotest.Server, opts ...Option) *Connector {
+	c, err := New(context.Background(), server.Client(t), workato.Development, opts...)
+	require.NoError(t, err)
+
+	return c
+}
+
+type syncResult struct {
+	resources    map[string][]*v2.Resource
+	entitlements []*v2.Entitlement
+	grants       []*v2.Grant
+}
+
+// principals returns the principals granted an entitlement as "type:id".
+func (r *syncResult) principals(resourceType, resourceId, slug string) []string {
+	entitlementId := resourceType + ":" + resourceId + ":" + slug
+
+	rv := make([]string, 0)
+	for _, g := range r.grants {
+		if g.Entitlement.Id == entitlementId {
+			rv = append(rv, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+		}
+	}
+
+	return rv
+}
+
+func (r *syncResult) resource(resourceType, resourceId string) *v2.Resource {
+	for _, resource := range r.resources[resourceType] {
+		if resource.Id.Resource == resourceId {
+			return resource
+		}
+	}
+
+	return nil
+}
+
+// syncAll walks every ResourceSyncer the same way the SDK does: resources of every type first, following
+// ChildResourceType annotations, then entitlements and grants.
+func syncAll(ctx context.Context, t *testing.T, c *Connector) *syncResult {
+	syncers := make(map[string]connectorbuilder.ResourceSyncer)
+	result := &syncResult{resources: make(map[string][]*v2.Resource)}
+
+	type listAction struct {
+		resourceType string
+		parent       *v2.ResourceId
+	}
+
+	var actions []listAction
+	for _, syncer := range c.ResourceSyncers(ctx) {
+		resourceType := syncer.ResourceType(ctx).Id
+		syncers[resourceType] = syncer
+		actions = append(actions, listAction{resourceType: resourceType})
+	}
+
+	var all []*v2.Resource
+
+	for len(actions) > 0 {
+		action := actions[0]
+		actions = actions[1:]
+
+		token := ""
+		for {
+			resources, nextToken, _, err := syncers[action.resourceType].List(ctx, action.parent, &pagination.Token{Token: token})
+			require.NoError(t, err)
+
+			for _, resource := range resources {
+				result.resources[action.resourceType] = append(result.resources[action.resourceType], resource)
+				all = append(all, resource)
+
+				for _, annotation := range resource.Annotations {
+					child := &v2.ChildResourceType{}
+					if annotation.MessageIs(child) {
+						require.NoError(t, annotation.UnmarshalTo(child))
+						actions = append(actions, listAction{resourceType: child.ResourceTypeId, parent: resource.Id})
+					}
+				}
+			}
+
+			if nextToken == "" {
+				break
+			}
+			token = nextToken
+		}
+	}
+
+	for _, resource := range all {
+		syncer := syncers[resource.Id.ResourceType]
+
+		token := ""
+		for {
+			entitlements, nextToken, _, err := syncer.Entitlements(ctx, resource, &pagination.Token{Token: token})
+			require.NoError(t, err)
+			result.entitlements = append(result.entitlements, entitlements...)
+
+			if nextToken == "" {
+				break
+			}
+			token = nextToken
+		}
+
+		token = ""
+		for {
+			grants, nextToken, _, err := syncer.Grants(ctx, resource, &pagination.Token{Token: token})
+			require.NoError(t, err)
+			result.grants = append(result.grants, grants...)
+
+			if nextToken == "" {
+				break
+			}
+			token = nextToken
+		}
+	}
+
+	return result
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newTestConnector(t, server, WithRecipes(true))
+
+	result := syncAll(ctx, t, c)
+
+	require.Len(t, result.resources[collaboratorResourceType.Id], 3)
+	require.Len(t, result.resources[projectResourceType.Id], 1)
+	require.Len(t, result.resources[privilegeResourceType.Id], len(workato.AllCompoundPrivileges()))
+	// The custom role plus the base roles.
+	require.Len(t, result.resources[roleResourceType.Id], 1+len(workato.BaseRoles))
+	require.NotNil(t, result.resource(folderResourceType.Id, "100"))
+	require.NotNil(t, result.resource(folderResourceType.Id, "101"))
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:1", "collaborator:2"},
+		result.principals(privilegeResourceType.Id, "Recipes-read", assignedEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:2"},
+		result.principals(privilegeResourceType.Id, "Recipes-run", assignedEntitlement),
+	)
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:1"},
+		result.principals(roleResourceType.Id, "Admin", collaboratorHasRoleEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:2"},
+		result.principals(roleResourceType.Id, "10", collaboratorHasRoleEntitlement),
+	)
+	require.Empty(t, result.principals(roleResourceType.Id, "Analyst", collaboratorHasRoleEntitlement))
+	require.ElementsMatch(t,
+		[]string{"privilege:Recipes-read", "privilege:Recipes-run", "privilege:Folders-read"},
+		result.principals(roleResourceType.Id, "10", roleHasPrivilegeEntitlement),
+	)
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:1", "collaborator:2"},
+		result.principals(folderResourceType.Id, "100", collaboratorAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:1"},
+		result.principals(folderResourceType.Id, "101", collaboratorAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"role:10"},
+		result.principals(folderResourceType.Id, "100", roleAccessEntitlement),
+	)
+
+	// The API Platform and on-prem groups are only synced when enabled.
+	require.Empty(t, result.resources[apiCollectionResourceType.Id])
+	require.Empty(t, result.resources[onPremGroupResourceType.Id])
+
+	require.Len(t, result.resources[recipeResourceType.Id], 2)
+	recipe := result.resource(recipeResourceType.Id, "1000")
+	require.NotNil(t, recipe)
+	require.Equal(t, "100", recipe.ParentResourceId.Resource)
+
+	recipeTrait, err := rs.GetAppTrait(recipe)
+	require.NoError(t, err)
+	profile := recipeTrait.Profile.AsMap()
+	require.Equal(t, true, profile["running"])
+	require.Equal(t, "alice", profile["owner"])
+	require.Equal(t, "", profile["last_run_at"])
+	require.Equal(t, []interface{}{float64(500)}, profile["connection_ids"])
+	require.Equal(t, []interface{}{"salesforce", "logger"}, profile["applications"])
+
+	// bob runs the recipes of his folder, alice edits them in every folder, carol's folder access is in prod.
+	require.ElementsMatch(t,
+		[]string{"collaborator:2"},
+		result.principals(recipeResourceType.Id, "1000", recipeRunEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:1"},
+		result.principals(recipeResourceType.Id, "1000", recipeEditEntitlement),
+	)
+	require.Empty(t, result.principals(recipeResourceType.Id, "1001", recipeRunEntitlement))
+	require.ElementsMatch(t,
+		[]string{"collaborator:1"},
+		result.principals(recipeResourceType.Id, "1001", recipeEditEntitlement),
+	)
+	require.Empty(t, result.principals(recipeResourceType.Id, "1000", recipeReadRunHistoryEntitlement))
+}
+
+func TestSyncFailsOnApiError(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.Fail(http.MethodGet, "/api/roles", workatotest.Fault{
+		StatusCode: http.StatusForbidden,
+		Body:       `{"message":"forbidden"}`,
+	})
+
+	c := newTestConnector(t, server)
+
+	_, _, _, err := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations).List(ctx, nil, &pagination.Token{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func roleEntitlement(ctx context.Context, t *testing.T, builder *roleBuilder, role *v2.Resource) *v2.Entitlement {
+	entitlements, _, _, err := builder.Entitlements(ctx, role, &pagination.Token{})
+	require.NoError(t, err)
+
+	for _, e := range entitlements {
+		if e.Slug == collaboratorHasRoleEntitlement {
+			return e
+		}
+	}
+
+	require.FailNow(t, "collaborator entitlement not found")
+	return nil
+}
+
+func TestRoleGrant(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newTestConnector(t, server)
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+	role, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+	require.NoError(t, err)
+
+	entitlement := roleEntitlement(ctx, t, builder, role)
+
+	grants, annos, err := builder.Grant(ctx, bob, entitlement)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, role.Id.Resource, grants[0].Entitlement.Resource.Id.Resource)
+	require.Equal(t, bob.Id.Resource, grants[0].Principal.Id.Resource)
+	require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}, server.CollaboratorRoles(2))
+
+	grants, annos, err = builder.Grant(ctx, bob, entitlement)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, role.Id.Resource, grants[0].Entitlement.Resource.Id.Resource)
+	require.Equal(t, bob.Id.Resource, grants[0].Principal.Id.Resource)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+}
+
+func TestCustomRoleGrantAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithRevokeFallbackRole("Analyst"))
+	require.NoError(t, err)
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+	builders := syncAll(ctx, t, c).resource(roleResourceType.Id, "10")
+	require.NotNil(t, builders)
+
+	alice, err := collaboratorResource(&client.Collaborator{Id: 1, Name: "alice"})
+	require.NoError(t, err)
+
+	// Custom roles are sent by name, their resource id is the Workato role id.
+	grants, _, err := builder.Grant(ctx, alice, roleEntitlement(ctx, t, builder, builders))
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, "10", grants[0].Entitlement.Resource.Id.Resource)
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, server.CollaboratorRoles(1))
+
+	_, err = builder.Revoke(ctx, grants[0])
+	require.NoError(t, err)
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Analyst"}}, server.CollaboratorRoles(1))
+
+	// Roles created after the sync are read from Workato.
+	server.AddRole(client.Role{Id: 12, Name: "Latecomers"})
+	latecomers, err := roleResource(&client.Role{Id: 12, Name: "Latecomers"})
+	require.NoError(t, err)
+
+	_, _, err = builder.Grant(ctx, alice, roleEntitlement(ctx, t, builder, latecomers))
+	require.NoError(t, err)
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Latecomers"}}, server.CollaboratorRoles(1))
+
+	// A role that no longer exists is no longer held.
+	missing, err := roleResource(&client.Role{Id: 99, Name: "Missing"})
+	require.NoError(t, err)
+
+	annos, err := builder.Revoke(ctx, grant.NewGrant(missing, collaboratorHasRoleEntitlement, alice.Id))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		server := newTestServer(t)
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.NoError(t, err)
+
+		// Validate only reads from the workspace.
+		for _, request := range server.Requests() {
+			require.True(t, strings.HasPrefix(request, http.MethodGet+" "), request)
+		}
+	})
+
+	t.Run("read only key", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodPut, "/api/members/2", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		c := newTestConnector(t, server)
+		_, err := c.Validate(ctx)
+		require.NoError(t, err)
+
+		// The missing scope is reported when provisioning.
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+		role, err := workatoBaseRoleResource(&workato.AdminRole)
+		require.NoError(t, err)
+
+		bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+		require.NoError(t, err)
+
+		_, _, err = builder.Grant(ctx, bob, roleEntitlement(ctx, t, builder, role))
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "Update collaborator roles")
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodGet, "/api/roles", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "Collaborator roles: List roles")
+	})
+
+	t.Run("missing API Platform scope", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodGet, "/api/api_access_profiles", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		// The scope is only needed when the API Platform is synced.
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.NoError(t, err)
+
+		c, err := New(ctx, server.Client(t), workato.Development, WithApiPlatform(true))
+		require.NoError(t, err)
+
+		_, err = c.Validate(ctx)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "API Platform: List API access profiles")
+	})
+
+	t.Run("missing recipes scope", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodGet, "/api/recipes", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		// The scope is only needed when recipes are synced.
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.NoError(t, err)
+
+		_, err = newTestConnector(t, server, WithRecipes(true)).Validate(ctx)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "Projects: List recipes")
+	})
+
+	t.Run("missing connections scope", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodGet, "/api/connections", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		// The scope is only needed when connections are synced.
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.NoError(t, err)
+
+		_, err = newTestConnector(t, server, WithConnections(true)).Validate(ctx)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "Projects: List connections")
+	})
+
+	t.Run("missing tables scope", func(t *testing.T) {
+		server := newTestServer(t)
+		server.Fail(http.MethodGet, "/api/data_tables", workatotest.Fault{
+			StatusCode: http.StatusForbidden,
+			Body:       `{"message":"forbidden"}`,
+		})
+
+		// The scope is only needed when tables are synced.
+		_, err := newTestConnector(t, server).Validate(ctx)
+		require.NoError(t, err)
+
+		_, err = newTestConnector(t, server, WithTables(true)).Validate(ctx)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.ErrorContains(t, err, "Tools: List data tables")
+	})
+
+	t.Run("unknown fallback role", func(t *testing.T) {
+		server := newTestServer(t)
+
+		c, err := New(ctx, server.Client(t), workato.Development, WithRevokeFallbackRole("Unknown"))
+		require.NoError(t, err)
+
+		_, err = c.Validate(ctx)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("unknown environment", func(t *testing.T) {
+		server := workatotest.NewServer(t)
+		server.AddCollaborator(
+			client.Collaborator{Id: 1, Name: "alice"},
+			&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Admin"},
+		)
+
+		c, err := New(ctx, server.Client(t), workato.Production)
+		require.NoError(t, err)
+
+		_, err = c.Validate(ctx)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestCollaboratorCachePartialResult(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.Fail(http.MethodGet, "/api/members/2/privileges", workatotest.Fault{
+		StatusCode: http.StatusInternalServerError,
+		Body:       `{"message":"injected"}`,
+	})
+
+	c := newTestConnector(t, server)
+	cache := newCollaboratorCache(c.client, c.envs, 2, c.roleCache, nil)
+
+	// Bob is left out with a warning, the other collaborators are cached.
+	require.NoError(t, cache.buildCache(ctx))
+
+	users, err := cache.getUsersByPrivilege(ctx, "Recipes-read")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, 1, users[0].User.Id)
+
+	// Without any collaborator the partial result would be an empty one.
+	for _, path := range []string{"/api/members/1/privileges", "/api/members/3/privileges"} {
+		server.Fail(http.MethodGet, path, workatotest.Fault{
+			StatusCode: http.StatusInternalServerError,
+			Body:       `{"message":"injected"}`,
+		})
+	}
+	cache.beginSync()
+	_, err = cache.getUsersByPrivilege(ctx, "Recipes-read")
+	require.Error(t, err)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestCollaboratorCacheSkipsRemovedCollaborator(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.Fail(http.MethodGet, "/api/members/2/privileges", workatotest.Fault{
+		StatusCode: http.StatusNotFound,
+		Body:       `{"message":"Not found"}`,
+	})
+
+	c := newTestConnector(t, server)
+	cache := newCollaboratorCache(c.client, c.envs, 2, c.roleCache, nil)
+
+	require.NoError(t, cache.buildCache(ctx))
+
+	users, err := cache.getUsersByPrivilege(ctx, "Recipes-read")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, 1, users[0].User.Id)
+
+	server.Fail(http.MethodGet, "/api/members", workatotest.Fault{
+		StatusCode: http.StatusInternalServerError,
+		Body:       `{"message":"injected"}`,
+	})
+
+	// The next sync rebuilds the cache from Workato instead of the cached responses.
+	cache.beginSync()
+	_, err = cache.getUsersByPrivilege(ctx, "Recipes-read")
+	require.Error(t, err)
+}
+
+func countRequests(server *workatotest.Server, request string) int {
+	count := 0
+	for _, r := range server.Requests() {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSyncBuildsCachesOnce(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newTestConnector(t, server)
+
+	syncAll(ctx, t, c)
+	require.Equal(t, 1, countRequests(server, "GET /api/members/1/privileges"))
+	// One page listed by the role syncer, one for the role cache.
+	require.Equal(t, 2, countRequests(server, "GET /api/roles"))
+
+	// A second sync on the same connector rebuilds the caches.
+	syncAll(ctx, t, c)
+	require.Equal(t, 2, countRequests(server, "GET /api/members/1/privileges"))
+}
+
+func TestGrantsAfterResume(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newTestConnector(t, server)
+
+	// A resumed sync can ask for grants without listing anything in this process.
+	role, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	grants, _, _, err := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations).Grants(ctx, role, &pagination.Token{})
+	require.NoError(t, err)
+	require.NotEmpty(t, grants)
+}
+
+func TestIncrementalSync(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	stateStore := store.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	newIncrementalConnector := func() *Connector {
+		c, err := New(ctx, server.Client(t), workato.Development, WithStore(stateStore), WithIncrementalSync(true))
+		require.NoError(t, err)
+		return c
+	}
+
+	_, err := newIncrementalConnector().collaboratorCache.getUsersByRole(ctx, workato.Development, "Admin")
+	require.NoError(t, err)
+	for _, id := range []string{"1", "2", "3"} {
+		require.Equal(t, 1, countRequests(server, "GET /api/members/"+id+"/privileges"))
+	}
+
+	_, err = server.Client(t).UpdateCollaboratorRoles(ctx, 2, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}})
+	require.NoError(t, err)
+
+	// Only bob changed, the next run reuses the privileges of everyone else.
+	admins, err := newIncrementalConnector().collaboratorCache.getUsersByRole(ctx, workato.Development, "Admin")
+	require.NoError(t, err)
+	require.Len(t, admins, 2)
+	require.Equal(t, 1, countRequests(server, "GET /api/members/1/privileges"))
+	require.Equal(t, 2, countRequests(server, "GET /api/members/2/privileges"))
+	require.Equal(t, 1, countRequests(server, "GET /api/members/3/privileges"))
+
+	requireFetched := func(counts ...int) {
+		t.Helper()
+
+		_, err := newIncrementalConnector().collaboratorCache.getUsersByRole(ctx, workato.Development, "Admin")
+		require.NoError(t, err)
+		for i, count := range counts {
+			require.Equal(t, count, countRequests(server, "GET /api/members/"+strconv.Itoa(i+1)+"/privileges"))
+		}
+	}
+
+	// A role change can change the access of any member without touching them.
+	builders, ok := server.Role(10)
+	require.True(t, ok)
+	updated, _, err := server.Client(t).UpdateRole(ctx, 10, client.RoleRequest{Name: builders.Name, FolderIDs: []int{100, 101}, Privileges: builders.Privileges})
+	require.NoError(t, err)
+	requireFetched(2, 3, 2)
+
+	// The watermark is the latest time Workato reported, not the local clock.
+	var snapshot collaboratorSnapshot
+	ok, err = stateStore.Load(collaboratorSnapshotKey, &snapshot)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, snapshot.Watermark.Equal(updated.UpdatedAt))
+
+	server.AddFolder(client.Folder{Id: 102, Name: "Partners", ParentId: 100, UpdatedAt: time.Now().UTC()})
+	requireFetched(3, 4, 3)
+
+	requireFetched(3, 4, 3)
+}
+
+func TestMultiEnvironmentSync(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.AddCollaborator(
+		client.Collaborator{Id: 4, Name: "dave", Email: "dave@example.com"},
+		&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Operator", FolderIDs: []int{100}},
+		&client.CollaboratorPrivilege{EnvironmentType: "prod", Name: "Admin", FolderIDs: []int{200}},
+	)
+
+	// Folders and projects of prod are only visible with the prod API key.
+	prod := workatotest.NewServer(t)
+	prod.AddProject(client.Project{Id: 2, Name: "Finance", FolderId: 200})
+	prod.AddFolder(client.Folder{Id: 200, Name: "Finance"})
+	prod.AddFolder(client.Folder{Id: 201, Name: "Invoices", ParentId: 200})
+	prod.AddDataTable(client.DataTable{Id: "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d", Name: "Payment terms", FolderId: 201})
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithRecipes(true), WithTables(true), WithEnvironmentClients(map[workato.Environment]*client.WorkatoClient{
+		workato.Production: prod.Client(t),
+	}))
+	require.NoError(t, err)
+
+	result := syncAll(ctx, t, c)
+
+	require.Len(t, result.resources[environmentResourceType.Id], 2)
+	require.NotNil(t, result.resource(projectResourceType.Id, "dev/1"))
+	require.NotNil(t, result.resource(projectResourceType.Id, "prod/2"))
+	for _, folderId := range []string{"dev/100", "dev/101", "prod/200", "prod/201"} {
+		require.NotNil(t, result.resource(folderResourceType.Id, folderId), folderId)
+	}
+	require.NotNil(t, result.resource(recipeResourceType.Id, "dev/1000"))
+	dataTable := result.resource(dataTableResourceType.Id, "prod/9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	require.NotNil(t, dataTable)
+	require.Equal(t, "prod/201", dataTable.ParentResourceId.Resource)
+	require.ElementsMatch(t,
+		[]string{"collaborator:2"},
+		result.principals(recipeResourceType.Id, "dev/1000", recipeRunEntitlement),
+	)
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:1"},
+		result.principals(roleResourceType.Id, "Admin", "collaborator-has-dev"),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:4"},
+		result.principals(roleResourceType.Id, "Admin", "collaborator-has-prod"),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:3"},
+		result.principals(roleResourceType.Id, "Analyst", "collaborator-has-prod"),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:3", "collaborator:4"},
+		result.principals(environmentResourceType.Id, "prod", environmentMemberEntitlement),
+	)
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:1", "collaborator:2", "collaborator:4"},
+		result.principals(folderResourceType.Id, "dev/100", collaboratorAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:4"},
+		result.principals(folderResourceType.Id, "prod/200", collaboratorAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"role:10"},
+		result.principals(folderResourceType.Id, "dev/100", roleAccessEntitlement),
+	)
+
+	_, err = c.Validate(ctx)
+	require.NoError(t, err)
+
+	// Granting the prod entitlement keeps the dev role.
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+	role, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+	require.NoError(t, err)
+
+	entitlements, _, _, err := builder.Entitlements(ctx, role, &pagination.Token{})
+	require.NoError(t, err)
+
+	var prodEntitlement *v2.Entitlement
+	for _, e := range entitlements {
+		if e.Slug == "collaborator-has-prod" {
+			prodEntitlement = e
+		}
+	}
+	require.NotNil(t, prodEntitlement)
+
+	_, _, err = builder.Grant(ctx, bob, prodEntitlement)
+	require.NoError(t, err)
+	require.ElementsMatch(t,
+		[]client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}, {EnvironmentType: "prod", RoleName: "Admin"}},
+		server.CollaboratorRoles(2),
+	)
+}
+
+func TestAccountCreationSchema(t *testing.T) {
+	ctx := context.Background()
+	c := newTestConnector(t, newTestServer(t))
+
+	metadata, err := c.Metadata(ctx)
+	require.NoError(t, err)
+
+	schema, ok := metadata.Profile.AsMap()["account_creation_schema"].(map[string]interface{})
+	require.True(t, ok)
+
+	required := func(name string) interface{} {
+		field, ok := schema[name].(map[string]interface{})
+		require.True(t, ok, name)
+		return field["required"]
+	}
+	require.Equal(t, true, required("email"))
+	require.Equal(t, true, required("role"))
+	require.Equal(t, false, required("environment"))
+}
+
+func TestCreateAccount(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	c := newTestConnector(t, server)
+	builder := newCollaboratorBuilder(c.client, c.envs, c.roleCache, c.dryRun)
+
+	accountInfo := func(profile map[string]interface{}) *v2.AccountInfo {
+		s, err := structpb.NewStruct(profile)
+		require.NoError(t, err)
+		return &v2.AccountInfo{Profile: s}
+	}
+
+	response, _, _, err := builder.CreateAccount(ctx, accountInfo(map[string]interface{}{
+		"email":      "dave@example.com",
+		"first_name": "Dave",
+		"last_name":  "Smith",
+		"role":       "Builders",
+	}), nil)
+	require.NoError(t, err)
+
+	result, ok := response.(*v2.CreateAccountResponse_ActionRequiredResult)
+	require.True(t, ok)
+	require.Equal(t, "invitation/dave@example.com", result.Resource.Id.Resource)
+
+	invitations := server.Invitations()
+	require.Len(t, invitations, 1)
+	require.Equal(t, "Dave Smith", invitations[0].Name)
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, invitations[0].Roles)
+
+	// The pending invitation is synced as a collaborator.
+	result2 := syncAll(ctx, t, c)
+	require.Len(t, result2.resources[collaboratorResourceType.Id], 4)
+	require.NotNil(t, result2.resource(collaboratorResourceType.Id, "invitation/dave@example.com"))
+
+	_, _, _, err = builder.CreateAccount(ctx, accountInfo(map[string]interface{}{
+		"email": "erin@example.com",
+		"role":  "Unknown",
+	}), nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, _, err = builder.CreateAccount(ctx, accountInfo(map[string]interface{}{
+		"email":       "erin@example.com",
+		"role":        "Admin",
+		"environment": "prod",
+	}), nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, _, err = builder.CreateAccount(ctx, accountInfo(map[string]interface{}{
+		"email": "alice@example.com",
+		"role":  "Admin",
+	}), nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestDeleteCollaborator(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.SetWorkspace(client.Workspace{Id: 1, Name: "workatotest", Email: "alice@example.com"})
+
+	server.AddRecipe(client.Recipe{Id: 1002, Name: "Sync invoices", FolderId: 101, UserId: 2})
+
+	c := newTestConnector(t, server)
+	builder := newCollaboratorBuilder(c.client, c.envs, c.roleCache, c.dryRun)
+
+	owned, err := builder.ownedRecipes(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1002"}, owned)
+
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "2"})
+	require.NoError(t, err)
+	require.Nil(t, server.CollaboratorRoles(2))
+	require.Equal(t, 1, countRequests(server, "DELETE /api/members/2"))
+
+	// Workato keeps bob as the owner, his recipe is reported as orphaned.
+	stillOwned, err := builder.ownedRecipes(ctx, 2)
+	require.NoError(t, err)
+	transferred, orphaned := recipeTransfers(owned, stillOwned)
+	require.Empty(t, transferred)
+	require.Equal(t, []string{"1002"}, orphaned)
+
+	transferred, orphaned = recipeTransfers(owned, []string{})
+	require.Equal(t, []string{"1002"}, transferred)
+	require.Empty(t, orphaned)
+
+	// Removing a collaborator twice is not an error.
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "2"})
+	require.NoError(t, err)
+	require.Equal(t, 1, countRequests(server, "DELETE /api/members/2"))
+
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.NotNil(t, server.CollaboratorRoles(1))
+
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "invitation/dave@example.com"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Without the owner's email nobody is removed.
+	server.SetWorkspace(client.Workspace{Id: 1, Name: "workatotest"})
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "3"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.NotNil(t, server.CollaboratorRoles(3))
+	require.Equal(t, 0, countRequests(server, "DELETE /api/members/3"))
+}
+
+func TestRoleRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	revoke := func(t *testing.T, c *Connector, role *v2.Resource, collaboratorId string) (annotations.Annotations, error) {
+		principal := &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: collaboratorId}
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+		return builder.Revoke(ctx, grant.NewGrant(role, collaboratorHasRoleEntitlement, principal))
+	}
+
+	admin, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	t.Run("keeps the other roles", func(t *testing.T) {
+		server := newTestServer(t)
+		server.AddCollaborator(
+			client.Collaborator{Id: 4, Name: "dave"},
+			&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Admin"},
+			&client.CollaboratorPrivilege{EnvironmentType: "prod", Name: "Analyst"},
+		)
+
+		_, err := revoke(t, newTestConnector(t, server), admin, "4")
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "prod", RoleName: "Analyst"}}, server.CollaboratorRoles(4))
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		server := newTestServer(t)
+
+		annos, err := revoke(t, newTestConnector(t, server), admin, "2")
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		require.Zero(t, countRequests(server, "PUT /api/members/2"))
+	})
+
+	t.Run("last role without fallback", func(t *testing.T) {
+		server := newTestServer(t)
+
+		_, err := revoke(t, newTestConnector(t, server), admin, "1")
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}, server.CollaboratorRoles(1))
+	})
+
+	t.Run("last role with fallback", func(t *testing.T) {
+		server := newTestServer(t)
+
+		c, err := New(ctx, server.Client(t), workato.Development, WithRevokeFallbackRole("Analyst"))
+		require.NoError(t, err)
+
+		_, err = revoke(t, c, admin, "1")
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Analyst"}}, server.CollaboratorRoles(1))
+	})
+}
+
+func TestRoleCreateAndDelete(t *testing.T) {
+	ctx := context.Background()
+
+	newRole := func(t *testing.T, name string, profile map[string]interface{}) *v2.Resource {
+		role, err := rs.NewRoleResource(name, roleResourceType, name, []rs.RoleTraitOption{rs.WithRoleProfile(profile)})
+		require.NoError(t, err)
+		return role
+	}
+
+	t.Run("create", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		created, _, err := builder.Create(ctx, newRole(t, "Operators", map[string]interface{}{
+			"inheritable": true,
+			"folder_ids":  []interface{}{100, "101"},
+			"privileges":  map[string]interface{}{"Recipes": []interface{}{"read", "run"}},
+		}))
+		require.NoError(t, err)
+
+		id, err := strconv.Atoi(created.Id.Resource)
+		require.NoError(t, err)
+
+		role, ok := server.Role(id)
+		require.True(t, ok)
+		require.Equal(t, "Operators", role.Name)
+		require.True(t, role.Inheritable)
+		require.Equal(t, []int{100, 101}, role.FolderIDs)
+		require.Equal(t, map[string][]string{"Recipes": {"read", "run"}}, role.Privileges)
+	})
+
+	t.Run("create with unknown privileges", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		_, _, err := builder.Create(ctx, newRole(t, "Operators", map[string]interface{}{
+			"privileges": map[string]interface{}{"Rockets": []interface{}{"launch"}},
+		}))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Zero(t, countRequests(server, "POST /api/roles"))
+
+		_, _, err = builder.Create(ctx, newRole(t, "Admin", nil))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		server := newTestServer(t)
+		server.AddRole(client.Role{Id: 11, Name: "Unused"})
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		_, err := builder.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "11"})
+		require.NoError(t, err)
+		_, ok := server.Role(11)
+		require.False(t, ok)
+
+		// Deleting a role twice is not an error.
+		_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "11"})
+		require.NoError(t, err)
+		require.Equal(t, 1, countRequests(server, "DELETE /api/roles/11"))
+	})
+
+	t.Run("delete a held role", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		_, err := builder.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "10"})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		_, ok := server.Role(10)
+		require.True(t, ok)
+
+		_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: workato.AdminRole.RoleName})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("delete a role granted after the last read", func(t *testing.T) {
+		server := newTestServer(t)
+		server.AddRole(client.Role{Id: 11, Name: "Unused"})
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		_, err := c.client.GetAllCollaborators(ctx)
+		require.NoError(t, err)
+		server.SetCollaboratorRoles(3, client.SimpleRole{EnvironmentType: "prod", RoleName: "Unused"})
+
+		_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "11"})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		_, ok := server.Role(11)
+		require.True(t, ok)
+	})
+}
+
+func TestRolePrivileges(t *testing.T) {
+	ctx := context.Background()
+
+	server := newTestServer(t)
+	c := newTestConnector(t, server)
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+	builders := syncAll(ctx, t, c).resource(roleResourceType.Id, "10")
+	require.NotNil(t, builders)
+
+	privilege := func(t *testing.T, id string) *v2.Resource {
+		compound, ok := workato.FindPrivilege(id)
+		require.True(t, ok)
+		resource, err := privilegeResource(&compound)
+		require.NoError(t, err)
+		return resource
+	}
+
+	entitlement := &v2.Entitlement{
+		Id:       "role:10:" + roleHasPrivilegeEntitlement,
+		Resource: builders,
+		Slug:     roleHasPrivilegeEntitlement,
+	}
+
+	_, _, err := builder.Grant(ctx, privilege(t, "Recipes-update"), entitlement)
+	require.NoError(t, err)
+	role, _ := server.Role(10)
+	require.Equal(t, []string{"read", "run", "update"}, role.Privileges["Recipes"])
+
+	_, annos, err := builder.Grant(ctx, privilege(t, "Recipes-update"), entitlement)
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+
+	// Recipes-run and Recipes-update need Recipes-read.
+	_, err = builder.Revoke(ctx, grant.NewGrant(builders, roleHasPrivilegeEntitlement, privilege(t, "Recipes-read").Id))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, _, err = builder.Grant(ctx, privilege(t, "Projects-update"), entitlement)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = builder.Revoke(ctx, grant.NewGrant(builders, roleHasPrivilegeEntitlement, privilege(t, "Folders-read").Id))
+	require.NoError(t, err)
+	role, _ = server.Role(10)
+	require.NotContains(t, role.Privileges, "Folders")
+
+	annos, err = builder.Revoke(ctx, grant.NewGrant(builders, roleHasPrivilegeEntitlement, privilege(t, "Folders-read").Id))
+	require.NoError(t, err)
+	require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+
+	admin, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	_, _, err = builder.Grant(ctx, privilege(t, "Recipes-update"), &v2.Entitlement{Resource: admin, Slug: roleHasPrivilegeEntitlement})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestFolderRoleAccess(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*workatotest.Server, *folderBuilder, *syncResult) {
+		server := newTestServer(t)
+		server.AddRole(client.Role{Id: 11, Name: "Inheritors", Inheritable: true, FolderIDs: []int{100}})
+		c := newTestConnector(t, server)
+		return server, newFolderBuilder(c.envs, c.collaboratorCache, c.roleCache, c.dryRun, c.roleLocks, c.folderChildren()), syncAll(ctx, t, c)
+	}
+
+	access := func(folder *v2.Resource) *v2.Entitlement {
+		return &v2.Entitlement{Id: "folder:" + folder.Id.Resource + ":" + roleAccessEntitlement, Resource: folder}
+	}
+
+	t.Run("grant and revoke", func(t *testing.T) {
+		server, builder, result := setup(t)
+		builders := result.resource(roleResourceType.Id, "10")
+		sales := result.resource(folderResourceType.Id, "100")
+		leads := result.resource(folderResourceType.Id, "101")
+
+		_, _, err := builder.Grant(ctx, builders, access(leads))
+		require.NoError(t, err)
+		role, _ := server.Role(10)
+		require.Equal(t, []int{100, 101}, role.FolderIDs)
+
+		_, annos, err := builder.Grant(ctx, builders, access(leads))
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+
+		_, err = builder.Revoke(ctx, grant.NewGrant(sales, roleAccessEntitlement, builders.Id))
+		require.NoError(t, err)
+		role, _ = server.Role(10)
+		require.Equal(t, []int{101}, role.FolderIDs)
+
+		annos, err = builder.Revoke(ctx, grant.NewGrant(sales, roleAccessEntitlement, builders.Id))
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+
+	t.Run("inheritable role", func(t *testing.T) {
+		server, builder, result := setup(t)
+		inheritors := result.resource(roleResourceType.Id, "11")
+		leads := result.resource(folderResourceType.Id, "101")
+
+		// The synced grants are the role folder_ids, access through the parent folder isn't one of them.
+		require.Contains(t, result.principals(folderResourceType.Id, "100", roleAccessEntitlement), roleResourceType.Id+":11")
+		require.NotContains(t, result.principals(folderResourceType.Id, "101", roleAccessEntitlement), roleResourceType.Id+":11")
+
+		_, err := builder.Revoke(ctx, grant.NewGrant(leads, roleAccessEntitlement, inheritors.Id))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, annos, err := builder.Grant(ctx, inheritors, access(leads))
+		require.NoError(t, err)
+		require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		role, _ := server.Role(11)
+		require.Equal(t, []int{100, 101}, role.FolderIDs)
+
+		// Removing the folder would leave the access the parent folder gives.
+		_, err = builder.Revoke(ctx, grant.NewGrant(leads, roleAccessEntitlement, inheritors.Id))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		role, _ = server.Role(11)
+		require.Equal(t, []int{100, 101}, role.FolderIDs)
+	})
+
+	t.Run("base role", func(t *testing.T) {
+		_, builder, result := setup(t)
+		admin, err := workatoBaseRoleResource(&workato.AdminRole)
+		require.NoError(t, err)
+
+		_, _, err = builder.Grant(ctx, admin, access(result.resource(folderResourceType.Id, "101")))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestRoleDryRun(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithDryRun(true), WithRevokeFallbackRole("Analyst"))
+	require.NoError(t, err)
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+	role, err := workatoBaseRoleResource(&workato.OperatorRole)
+	require.NoError(t, err)
+
+	bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+	require.NoError(t, err)
+
+	// The changes are logged and refused, a grant is never reported as done when nothing was applied.
+	grants, _, err := builder.Grant(ctx, bob, roleEntitlement(ctx, t, builder, role))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "dry run")
+	require.Empty(t, grants)
+
+	admin, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	_, err = builder.Revoke(ctx, grant.NewGrant(admin, collaboratorHasRoleEntitlement, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "1"}))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "dry run")
+
+	require.Zero(t, countRequests(server, "PUT /api/members/1"))
+	require.Zero(t, countRequests(server, "PUT /api/members/2"))
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}, server.CollaboratorRoles(1))
+	require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, server.CollaboratorRoles(2))
+}
+
+func TestDryRunOnlyReads(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	server.AddRole(client.Role{Id: 11, Name: "Unused"})
+	server.SetWorkspace(client.Workspace{Id: 1, Name: "workatotest", Email: "alice@example.com"})
+
+	c, err := New(ctx, server.Client(t, client.WithDryRun(true)), workato.Development, WithDryRun(true))
+	require.NoError(t, err)
+	result := syncAll(ctx, t, c)
+
+	roles := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+	folders := newFolderBuilder(c.envs, c.collaboratorCache, c.roleCache, c.dryRun, c.roleLocks, c.folderChildren())
+	collaborators := newCollaboratorBuilder(c.client, c.envs, c.roleCache, c.dryRun)
+
+	builders := result.resource(roleResourceType.Id, "10")
+
+	compound, ok := workato.FindPrivilege("Recipes-update")
+	require.True(t, ok)
+	privilege, err := privilegeResource(&compound)
+	require.NoError(t, err)
+
+	// Every provisioning call fails the same way in dry run.
+	requireDryRun := func(err error) {
+		t.Helper()
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.ErrorContains(t, err, "baton-workato: dry run")
+	}
+
+	_, _, err = roles.Grant(ctx, privilege, &v2.Entitlement{Resource: builders, Slug: roleHasPrivilegeEntitlement})
+	requireDryRun(err)
+
+	_, _, err = folders.Grant(ctx, builders, &v2.Entitlement{Resource: result.resource(folderResourceType.Id, "101"), Slug: roleAccessEntitlement})
+	requireDryRun(err)
+
+	_, err = folders.Revoke(ctx, grant.NewGrant(result.resource(folderResourceType.Id, "100"), roleAccessEntitlement, builders.Id))
+	requireDryRun(err)
+
+	role, err := rs.NewRoleResource("Operators", roleResourceType, "Operators", nil)
+	require.NoError(t, err)
+	_, _, err = roles.Create(ctx, role)
+	requireDryRun(err)
+
+	_, err = roles.Delete(ctx, &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: "11"})
+	requireDryRun(err)
+
+	_, err = collaborators.Delete(ctx, &v2.ResourceId{ResourceType: collaboratorResourceType.Id, Resource: "2"})
+	requireDryRun(err)
+
+	for _, request := range server.Requests() {
+		require.True(t, strings.HasPrefix(request, http.MethodGet+" "), request)
+	}
+
+	updated, _ := server.Role(10)
+	require.Equal(t, []int{100}, updated.FolderIDs)
+	_, ok = server.Role(11)
+	require.True(t, ok)
+	require.NotNil(t, server.CollaboratorRoles(2))
+}
+
+func TestEnvRolesDiff(t *testing.T) {
+	current := []client.SimpleRole{
+		{EnvironmentType: "dev", RoleName: "Admin"},
+		{EnvironmentType: "prod", RoleName: "Analyst"},
+		{EnvironmentType: "test", RoleName: "Operator"},
+	}
+	proposed := []client.SimpleRole{
+		{EnvironmentType: "dev", RoleName: "Builders"},
+		{EnvironmentType: "test", RoleName: "Operator"},
+	}
+
+	require.Equal(t, "dev: Admin -> Builders, prod: - Analyst, test: Operator", envRolesDiff(current, proposed))
+	require.Equal(t, "dev: + Admin", envRolesDiff(nil, current[:1]))
+}
+
+func TestConcurrentRoleGrants(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	// Slow reads leave room for the grants to interleave.
+	server.After(http.MethodGet, "/api/members/2/privileges", 0, func() {
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithEnvironmentClients(map[workato.Environment]*client.WorkatoClient{
+		workato.Production: workatotest.NewServer(t).Client(t),
+	}))
+	require.NoError(t, err)
+	builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+	bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+	require.NoError(t, err)
+
+	grantRole := func(role workato.Role, env workato.Environment) error {
+		resource, err := workatoBaseRoleResource(&role)
+		if err != nil {
+			return err
+		}
+
+		entitlement := &v2.Entitlement{Resource: resource, Slug: c.envs.roleEntitlement(env)}
+		_, _, err = builder.Grant(ctx, bob, entitlement)
+		return err
+	}
+
+	errs := make(chan error, 2)
+	go func() { errs <- grantRole(workato.OperatorRole, workato.Development) }()
+	go func() { errs <- grantRole(workato.AnalystRole, workato.Production) }()
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	// Serialized grants never overwrite each other, so none of them is retried.
+	require.Equal(t, 2, countRequests(server, "PUT /api/members/2"))
+
+	require.ElementsMatch(t, []client.SimpleRole{
+		{EnvironmentType: "dev", RoleName: "Operator"},
+		{EnvironmentType: "prod", RoleName: "Analyst"},
+	}, server.CollaboratorRoles(2))
+}
+
+func TestRoleGrantVerifiesUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	grantAdmin := func(t *testing.T, server *workatotest.Server) error {
+		c := newTestConnector(t, server)
+		builder := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+
+		role, err := workatoBaseRoleResource(&workato.AdminRole)
+		require.NoError(t, err)
+
+		bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+		require.NoError(t, err)
+
+		_, _, err = builder.Grant(ctx, bob, roleEntitlement(ctx, t, builder, role))
+		return err
+	}
+
+	t.Run("retries on top of a concurrent change", func(t *testing.T) {
+		server := newTestServer(t)
+		server.After(http.MethodPut, "/api/members/2", 1, func() {
+			server.SetCollaboratorRoles(2,
+				client.SimpleRole{EnvironmentType: "dev", RoleName: "Builders"},
+				client.SimpleRole{EnvironmentType: "prod", RoleName: "Analyst"},
+			)
+		})
+
+		require.NoError(t, grantAdmin(t, server))
+		require.Equal(t, 2, countRequests(server, "PUT /api/members/2"))
+		require.ElementsMatch(t, []client.SimpleRole{
+			{EnvironmentType: "dev", RoleName: "Admin"},
+			{EnvironmentType: "prod", RoleName: "Analyst"},
+		}, server.CollaboratorRoles(2))
+	})
+
+	t.Run("fails when the roles keep changing", func(t *testing.T) {
+		server := newTestServer(t)
+		server.After(http.MethodPut, "/api/members/2", 0, func() {
+			server.SetCollaboratorRoles(2, client.SimpleRole{EnvironmentType: "dev", RoleName: "Builders"})
+		})
+
+		err := grantAdmin(t, server)
+		require.Equal(t, codes.Aborted, status.Code(err))
+		require.Equal(t, maxRoleUpdateAttempts, countRequests(server, "PUT /api/members/2"))
+	})
+}
+
+func TestCustomRoleUpdates(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, server *workatotest.Server) (*roleBuilder, *folderBuilder, *syncResult) {
+		c := newTestConnector(t, server)
+		roles := newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+		folders := newFolderBuilder(c.envs, c.collaboratorCache, c.roleCache, c.dryRun, c.roleLocks, c.folderChildren())
+		return roles, folders, syncAll(ctx, t, c)
+	}
+
+	recipesUpdate := func(t *testing.T) *v2.Resource {
+		compound, ok := workato.FindPrivilege("Recipes-update")
+		require.True(t, ok)
+		resource, err := privilegeResource(&compound)
+		require.NoError(t, err)
+		return resource
+	}
+
+	t.Run("concurrent updates of a role", func(t *testing.T) {
+		server := newTestServer(t)
+
+		// Slow reads leave room for the updates to interleave.
+		server.After(http.MethodGet, "/api/roles", 0, func() {
+			time.Sleep(20 * time.Millisecond)
+		})
+
+		roles, folders, result := setup(t, server)
+		builders := result.resource(roleResourceType.Id, "10")
+
+		errs := make(chan error, 2)
+		go func() {
+			_, _, err := roles.Grant(ctx, recipesUpdate(t), &v2.Entitlement{Resource: builders, Slug: roleHasPrivilegeEntitlement})
+			errs <- err
+		}()
+		go func() {
+			_, _, err := folders.Grant(ctx, builders, &v2.Entitlement{Resource: result.resource(folderResourceType.Id, "101"), Slug: roleAccessEntitlement})
+			errs <- err
+		}()
+		require.NoError(t, <-errs)
+		require.NoError(t, <-errs)
+
+		// Serialized updates never overwrite each other, so none of them is retried.
+		require.Equal(t, 2, countRequests(server, "PUT /api/roles/10"))
+
+		role, _ := server.Role(10)
+		require.ElementsMatch(t, []int{100, 101}, role.FolderIDs)
+		require.ElementsMatch(t, []string{"read", "run", "update"}, role.Privileges["Recipes"])
+	})
+
+	t.Run("retries on top of a concurrent change", func(t *testing.T) {
+		server := newTestServer(t)
+		server.AddFolder(client.Folder{Id: 102, Name: "Marketing"})
+		server.After(http.MethodPut, "/api/roles/10", 1, func() {
+			server.SetRoleFolders(10, 100, 102)
+		})
+
+		_, folders, result := setup(t, server)
+		builders := result.resource(roleResourceType.Id, "10")
+
+		_, err := folders.Revoke(ctx, grant.NewGrant(result.resource(folderResourceType.Id, "100"), roleAccessEntitlement, builders.Id))
+		require.NoError(t, err)
+		require.Equal(t, 2, countRequests(server, "PUT /api/roles/10"))
+
+		role, _ := server.Role(10)
+		require.Equal(t, []int{102}, role.FolderIDs)
+	})
+
+	t.Run("fails when the role keeps changing", func(t *testing.T) {
+		server := newTestServer(t)
+		server.After(http.MethodPut, "/api/roles/10", 0, func() {
+			server.SetRoleFolders(10, 100)
+		})
+
+		_, folders, result := setup(t, server)
+		builders := result.resource(roleResourceType.Id, "10")
+
+		_, _, err := folders.Grant(ctx, builders, &v2.Entitlement{Resource: result.resource(folderResourceType.Id, "101"), Slug: roleAccessEntitlement})
+		require.Equal(t, codes.Aborted, status.Code(err))
+		require.Equal(t, maxRoleUpdateAttempts, countRequests(server, "PUT /api/roles/10"))
+	})
+}
+
+func TestRoleElevation(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	stateStore := store.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	// Every step uses a new connector, the elevations must survive a restart.
+	newBuilder := func(t *testing.T) *roleBuilder {
+		c, err := New(ctx, server.Client(t), workato.Development, WithStore(stateStore), WithElevation([]string{"Admin"}, time.Hour))
+		require.NoError(t, err)
+		return newRoleBuilder(c.client, c.envs, c.collaboratorCache, c.roleCache, c.fallbackRole, c.dryRun, c.collaboratorLocks, c.roleLocks, c.elevations)
+	}
+
+	admin, err := workatoBaseRoleResource(&workato.AdminRole)
+	require.NoError(t, err)
+
+	bob, err := collaboratorResource(&client.Collaborator{Id: 2, Name: "bob"})
+	require.NoError(t, err)
+
+	grantAdmin := func(t *testing.T) *v2.Grant {
+		builder := newBuilder(t)
+		grants, _, err := builder.Grant(ctx, bob, roleEntitlement(ctx, t, builder, admin))
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}, server.CollaboratorRoles(2))
+		return grants[0]
+	}
+
+	t.Run("revoke restores the previous role", func(t *testing.T) {
+		adminGrant := grantAdmin(t)
+
+		metadata := &v2.GrantMetadata{}
+		grantAnnotations := annotations.Annotations(adminGrant.Annotations)
+		ok, err := grantAnnotations.Pick(metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "Builders", metadata.Metadata.AsMap()["previous_role"])
+
+		_, err = newBuilder(t).Revoke(ctx, adminGrant)
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, server.CollaboratorRoles(2))
+	})
+
+	t.Run("expired elevation is restored on sync", func(t *testing.T) {
+		grantAdmin(t)
+
+		builder := newBuilder(t)
+		_, _, _, err := builder.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Admin"}}, server.CollaboratorRoles(2))
+
+		builder.elevations.now = func() time.Time {
+			return time.Now().Add(2 * time.Hour)
+		}
+
+		_, _, _, err = builder.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, server.CollaboratorRoles(2))
+
+		expired, err := builder.elevations.expired()
+		require.NoError(t, err)
+		require.Empty(t, expired)
+	})
+
+	t.Run("failed grant forgets the elevation", func(t *testing.T) {
+		server.Fail(http.MethodPut, "/api/members/2", workatotest.Fault{
+			StatusCode: http.StatusBadRequest,
+			Body:       `{"message":"Invalid role"}`,
+			Times:      1,
+		})
+
+		builder := newBuilder(t)
+		_, _, err := builder.Grant(ctx, bob, roleEntitlement(ctx, t, builder, admin))
+		require.Error(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "dev", RoleName: "Builders"}}, server.CollaboratorRoles(2))
+
+		_, ok, err := builder.elevations.get(2, "dev")
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("role added to a new environment is removed on revoke and expiry", func(t *testing.T) {
+		builder := newBuilder(t)
+
+		carol, err := collaboratorResource(&client.Collaborator{Id: 3, Name: "carol"})
+		require.NoError(t, err)
+
+		adminGrant, _, err := builder.Grant(ctx, carol, roleEntitlement(ctx, t, builder, admin))
+		require.NoError(t, err)
+
+		elevated, ok, err := builder.elevations.get(3, "dev")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Empty(t, elevated.PreviousRole)
+
+		_, err = builder.Revoke(ctx, adminGrant[0])
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "prod", RoleName: "Analyst"}}, server.CollaboratorRoles(3))
+
+		_, ok, err = builder.elevations.get(3, "dev")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, _, err = builder.Grant(ctx, carol, roleEntitlement(ctx, t, builder, admin))
+		require.NoError(t, err)
+
+		builder.elevations.now = func() time.Time {
+			return time.Now().Add(2 * time.Hour)
+		}
+
+		_, _, _, err = builder.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Equal(t, []client.SimpleRole{{EnvironmentType: "prod", RoleName: "Analyst"}}, server.CollaboratorRoles(3))
+	})
+}
+
+func TestConnectionGrants(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	integrators := map[string][]string{"Connections": {"read", "update"}}
+	server.AddRole(client.Role{Id: 11, Name: "Integrators", FolderIDs: []int{100}, Privileges: integrators})
+	server.AddCollaborator(
+		client.Collaborator{Id: 4, Name: "dave", Email: "dave@example.com"},
+		&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Integrators", Privileges: integrators, FolderIDs: []int{100}},
+	)
+
+	authorizedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	server.AddConnection(client.Connection{
+		Id:                  700,
+		Name:                "ERP",
+		Application:         "netsuite",
+		FolderId:            100,
+		AuthorizationStatus: "success",
+		AuthorizedAt:        &authorizedAt,
+	})
+	server.AddConnection(client.Connection{Id: 701, Name: "Warehouse", Application: "snowflake", FolderId: 101})
+
+	result := syncAll(ctx, t, newTestConnector(t, server, WithConnections(true)))
+
+	require.Len(t, result.resources[connectionResourceType.Id], 2)
+	connection := result.resource(connectionResourceType.Id, "700")
+	require.NotNil(t, connection)
+	require.Equal(t, "100", connection.ParentResourceId.Resource)
+
+	connectionTrait, err := rs.GetAppTrait(connection)
+	require.NoError(t, err)
+	profile := connectionTrait.Profile.AsMap()
+	require.Equal(t, "netsuite", profile["application"])
+	require.Equal(t, "success", profile["authorization_status"])
+	require.Equal(t, "2024-01-02T03:04:05Z", profile["authorized_at"])
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:4", "role:11"},
+		result.principals(connectionResourceType.Id, "700", connectionReadEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:4", "role:11"},
+		result.principals(connectionResourceType.Id, "700", connectionEditEntitlement),
+	)
+	require.Empty(t, result.principals(connectionResourceType.Id, "700", connectionDeleteEntitlement))
+	// Integrators don't have access to the folder of the warehouse connection.
+	require.Empty(t, result.principals(connectionResourceType.Id, "701", connectionReadEntitlement))
+}
+
+func TestApiPlatform(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	server.AddApiCollection(client.ApiCollection{Id: 1, Name: "Orders API"})
+	server.AddApiCollection(client.ApiCollection{Id: 2, Name: "Billing API"})
+	server.AddApiClient(client.ApiClient{Id: 1, Name: "Partner portal"})
+	server.AddApiClient(client.ApiClient{Id: 2, Name: "Mobile app"})
+	server.AddApiAccessProfile(client.ApiAccessProfile{Id: 1, Name: "Partner orders", ApiClientId: 1, ApiCollectionIds: []int{1}, Active: true})
+	server.AddApiAccessProfile(client.ApiAccessProfile{Id: 2, Name: "Mobile", ApiClientId: 2, ApiCollectionIds: []int{1, 2}})
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithApiPlatform(true))
+	require.NoError(t, err)
+
+	result := syncAll(ctx, t, c)
+
+	require.Len(t, result.resources[apiCollectionResourceType.Id], 2)
+	require.Len(t, result.resources[apiClientResourceType.Id], 2)
+	require.Len(t, result.resources[apiAccessProfileResourceType.Id], 2)
+
+	require.ElementsMatch(t,
+		[]string{"api_access_profile:1", "api_access_profile:2"},
+		result.principals(apiCollectionResourceType.Id, "1", apiCollectionAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"api_access_profile:2"},
+		result.principals(apiCollectionResourceType.Id, "2", apiCollectionAccessEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"api_client:1"},
+		result.principals(apiAccessProfileResourceType.Id, "1", assignedEntitlement),
+	)
+	// The mobile profile is disabled.
+	require.Empty(t, result.principals(apiAccessProfileResourceType.Id, "2", assignedEntitlement))
+
+	collections := newApiCollectionBuilder(c.envs, c.accessProfiles, c.dryRun)
+	profiles := newApiAccessProfileBuilder(c.envs, c.accessProfiles, c.dryRun)
+
+	entitlementOf := func(t *testing.T, syncer connectorbuilder.ResourceSyncer, resource *v2.Resource) *v2.Entitlement {
+		entitlements, _, _, err := syncer.Entitlements(ctx, resource, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, entitlements, 1)
+		return entitlements[0]
+	}
+
+	partnerOrders := result.resource(apiAccessProfileResourceType.Id, "1")
+	ordersAccess := entitlementOf(t, collections, result.resource(apiCollectionResourceType.Id, "1"))
+	billingAccess := entitlementOf(t, collections, result.resource(apiCollectionResourceType.Id, "2"))
+
+	t.Run("collections of an access profile", func(t *testing.T) {
+		_, annos, err := collections.Grant(ctx, partnerOrders, billingAccess)
+		require.NoError(t, err)
+		require.False(t, annos.Contains(&v2.GrantAlreadyExists{}))
+
+		_, annos, err = collections.Grant(ctx, partnerOrders, billingAccess)
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+
+		_, err = collections.Revoke(ctx, grant.NewGrant(ordersAccess.Resource, apiCollectionAccessEntitlement, partnerOrders.Id))
+		require.NoError(t, err)
+
+		profile, ok := server.ApiAccessProfile(1)
+		require.True(t, ok)
+		require.Equal(t, []int{2}, profile.ApiCollectionIds)
+
+		// An access profile keeps at least one collection.
+		_, err = collections.Revoke(ctx, grant.NewGrant(billingAccess.Resource, apiCollectionAccessEntitlement, partnerOrders.Id))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("client of an access profile", func(t *testing.T) {
+		mobile := result.resource(apiAccessProfileResourceType.Id, "2")
+		partnerPortal := result.resource(apiClientResourceType.Id, "1")
+		mobileApp := result.resource(apiClientResourceType.Id, "2")
+
+		// The partner orders profile is active for the partner portal, moving it would revoke the portal's access.
+		_, _, err := profiles.Grant(ctx, mobileApp, entitlementOf(t, profiles, partnerOrders))
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		profile, ok := server.ApiAccessProfile(1)
+		require.True(t, ok)
+		require.Equal(t, 1, profile.ApiClientId)
+		require.True(t, profile.Active)
+
+		// The mobile profile is disabled, it can be moved.
+		_, _, err = profiles.Grant(ctx, partnerPortal, entitlementOf(t, profiles, mobile))
+		require.NoError(t, err)
+
+		profile, ok = server.ApiAccessProfile(2)
+		require.True(t, ok)
+		require.Equal(t, 1, profile.ApiClientId)
+		require.True(t, profile.Active)
+
+		mobileGrant := grant.NewGrant(mobile, assignedEntitlement, partnerPortal.Id)
+
+		_, err = profiles.Revoke(ctx, mobileGrant)
+		require.NoError(t, err)
+
+		profile, ok = server.ApiAccessProfile(2)
+		require.True(t, ok)
+		require.Equal(t, 1, profile.ApiClientId)
+		require.False(t, profile.Active)
+
+		annos, err := profiles.Revoke(ctx, mobileGrant)
+		require.NoError(t, err)
+		require.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+}
+
+func TestOnPrem(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	operators := map[string][]string{"On-prem groups & agents": {"read", "update"}}
+	server.AddRole(client.Role{Id: 12, Name: "Operators", Privileges: operators})
+	server.AddCollaborator(
+		client.Collaborator{Id: 4, Name: "dave", Email: "dave@example.com"},
+		&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Operators", Privileges: operators},
+	)
+
+	server.AddOnPremGroup(client.OnPremGroup{Id: 1, Name: "DC East"})
+	server.AddOnPremAgent(client.OnPremAgent{Id: 10, Name: "east-1", OnPremGroupId: 1, Status: "online", Os: "linux", Version: "2.1.0", Enabled: true})
+
+	onPremGroupId := 1
+	server.AddConnection(client.Connection{Id: 700, Name: "ERP", Application: "sap", FolderId: 100, OnPremGroupId: &onPremGroupId})
+	server.AddConnection(client.Connection{Id: 701, Name: "CRM", Application: "salesforce", FolderId: 100})
+
+	c, err := New(ctx, server.Client(t), workato.Development, WithOnPrem(true), WithConnections(true))
+	require.NoError(t, err)
+
+	result := syncAll(ctx, t, c)
+
+	require.Len(t, result.resources[onPremGroupResourceType.Id], 1)
+	agent := result.resource(onPremAgentResourceType.Id, "10")
+	require.NotNil(t, agent)
+	require.Equal(t, "1", agent.ParentResourceId.Resource)
+
+	agentTrait, err := rs.GetAppTrait(agent)
+	require.NoError(t, err)
+	profile := agentTrait.Profile.AsMap()
+	require.Equal(t, "online", profile["status"])
+	require.Equal(t, "linux", profile["os"])
+	require.Equal(t, "2.1.0", profile["version"])
+
+	for _, resourceType := range []string{onPremGroupResourceType.Id, onPremAgentResourceType.Id} {
+		resourceId := "1"
+		if resourceType == onPremAgentResourceType.Id {
+			resourceId = "10"
+		}
+
+		require.ElementsMatch(t,
+			[]string{"collaborator:4", "role:12"},
+			result.principals(resourceType, resourceId, onPremReadEntitlement),
+		)
+		require.ElementsMatch(t,
+			[]string{"collaborator:4", "role:12"},
+			result.principals(resourceType, resourceId, onPremEditEntitlement),
+		)
+		require.Empty(t, result.principals(resourceType, resourceId, onPremDeleteEntitlement))
+	}
+
+	// Only the ERP connection goes through the agents, whoever can use it reaches the on-prem system.
+	require.ElementsMatch(t,
+		[]string{"connection:700"},
+		result.principals(onPremGroupResourceType.Id, "1", onPremConnectionEntitlement),
+	)
+
+	for _, g := range result.grants {
+		if g.Entitlement.Id != "onprem_group:1:connection" {
+			continue
+		}
+
+		expandable := &v2.GrantExpandable{}
+		grantAnnotations := annotations.Annotations(g.Annotations)
+		ok, err := grantAnnotations.Pick(expandable)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Contains(t, expandable.EntitlementIds, "connection:700:read")
+	}
+}
+
+func TestTables(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+
+	stewards := map[string][]string{
+		"Lookup tables": {"read", "update_records"},
+	}
+	server.AddRole(client.Role{Id: 12, Name: "Stewards", FolderIDs: []int{101}, Privileges: stewards})
+	server.AddCollaborator(
+		client.Collaborator{Id: 5, Name: "erin", Email: "erin@example.com"},
+		&client.CollaboratorPrivilege{EnvironmentType: "dev", Name: "Stewards", Privileges: stewards, FolderIDs: []int{101}},
+	)
+
+	// The country codes table only belongs to the Sales project, it's listed in the folder of the project.
+	server.AddLookupTable(client.LookupTable{Id: 800, Name: "Country codes", ProjectId: 1})
+	server.AddLookupTable(client.LookupTable{Id: 801, Name: "Lead tiers", ProjectId: 1, FolderId: 101})
+	server.AddDataTable(client.DataTable{
+		Id:       "3f1c2a9e-8d4b-4c6a-9f0e-1a2b3c4d5e6f",
+		Name:     "Scores",
+		FolderId: 101,
+		Schema:   []client.DataTableColumn{{Name: "lead", Type: "string"}, {Name: "score", Type: "integer"}},
+	})
+
+	result := syncAll(ctx, t, newTestConnector(t, server, WithTables(true)))
+
+	require.Equal(t, 1, countRequests(server, "GET /api/lookup_tables"))
+	require.Equal(t, 1, countRequests(server, "GET /api/data_tables"))
+
+	require.Len(t, result.resources[lookupTableResourceType.Id], 2)
+	require.Equal(t, "100", result.resource(lookupTableResourceType.Id, "800").ParentResourceId.Resource)
+	require.Equal(t, "101", result.resource(lookupTableResourceType.Id, "801").ParentResourceId.Resource)
+
+	require.ElementsMatch(t,
+		[]string{"collaborator:5", "role:12"},
+		result.principals(lookupTableResourceType.Id, "801", lookupTableReadEntitlement),
+	)
+	require.ElementsMatch(t,
+		[]string{"collaborator:5", "role:12"},
+		result.principals(lookupTableResourceType.Id, "801", lookupTableUpdateRecordsEntitlement),
+	)
+	require.Empty(t, result.principals(lookupTableResourceType.Id, "801", lookupTableUpdateSchemaEntitlement))
+	require.Empty(t, result.principals(lookupTableResourceType.Id, "801", lookupTableDeleteEntitlement))
+	// Stewards don't have access to the folder of the Sales project.
+	require.Empty(t, result.principals(lookupTableResourceType.Id, "800", lookupTableReadEntitlement))
+
+	dataTable := result.resource(dataTableResourceType.Id, "3f1c2a9e-8d4b-4c6a-9f0e-1a2b3c4d5e6f")
+	require.NotNil(t, dataTable)
+	require.Equal(t, "101", dataTable.ParentResourceId.Resource)
+
+	dataTableTrait, err := rs.GetAppTrait(dataTable)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"lead", "score"}, dataTableTrait.Profile.AsMap()["columns"])
+
+	// Access to the folder of the table gives access to it, alice's role has the folder too.
+	require.ElementsMatch(t,
+		[]string{"collaborator:1", "collaborator:5", "role:12"},
+		result.principals(dataTableResourceType.Id, dataTable.Id.Resource, dataTableAccessEntitlement),
+	)
+}
@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// dataTableAccessEntitlement is given by access to the folder of the table.
+// The data tables privileges of a role aren't modeled, the privilege list of Workato doesn't document them.
+const dataTableAccessEntitlement = "access"
+
+type dataTableBuilder struct {
+	envs      *environments
+	cache     *collaboratorCache
+	roleCache *roleCache
+	tables    *tableCache[client.DataTable]
+}
+
+func (o *dataTableBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return dataTableResourceType
+}
+
+// List returns the data tables directly in a folder.
+func (o *dataTableBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil {
+		o.tables.beginSync()
+		return nil, "", nil, nil
+	}
+
+	if parentResourceID.ResourceType != folderResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	env, _, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	tables, err := o.tables.getTablesByFolder(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(tables))
+
+	for _, table := range tables {
+		us, err := dataTableResource(&table, o.envs.rawResourceId(env, table.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (o *dataTableBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(collaboratorResourceType, roleResourceType),
+		entitlement.WithDescription(fmt.Sprintf("Access to the folder of %s %s", resource.Id.ResourceType, resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s %s", dataTableAccessEntitlement, resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{entitlement.NewPermissionEntitlement(resource, dataTableAccessEntitlement, assigmentOptions...)}, "", nil, nil
+}
+
+// Grants gives the access entitlement to the collaborators and roles with access to the folder of the table.
+// Roles belong to the workspace, their folder ids are folders of the main environment.
+func (o *dataTableBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, _, err := o.envs.parseRawResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, fmt.Errorf("baton-workato: %s %s has no folder", resource.Id.ResourceType, resource.Id.Resource)
+	}
+
+	_, folderId, err := o.envs.parseResourceId(resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+
+	collaborators, err := o.cache.getUsersByFolder(ctx, resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, collaborator := range collaborators {
+		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		// To update collaborator access, the role must be updated
+		rv = append(rv, grant.NewGrant(resource, dataTableAccessEntitlement, collaboratorId, grant.WithAnnotation(&v2.GrantImmutable{})))
+	}
+
+	roles := make([]*client.Role, 0)
+	if env == o.envs.main {
+		roles, err = o.roleCache.getRoleByFolder(ctx, folderId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	for _, role := range roles {
+		roleId, err := rs.NewResourceID(roleResourceType, role.Id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		// Access is changed through the role folders.
+		rv = append(rv, grant.NewGrant(resource, dataTableAccessEntitlement, roleId, grant.WithAnnotation(&v2.GrantImmutable{})))
+	}
+
+	return rv, "", nil, nil
+}
+
+func newDataTableBuilder(envs *environments, cache *collaboratorCache, roleCache *roleCache, tables *tableCache[client.DataTable]) *dataTableBuilder {
+	return &dataTableBuilder{
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+		tables:    tables,
+	}
+}
+
+func dataTableResource(table *client.DataTable, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	columns := make([]interface{}, 0, len(table.Schema))
+	for _, column := range table.Schema {
+		columns = append(columns, column.Name)
+	}
+
+	profile := map[string]interface{}{
+		"id":         table.Id,
+		"name":       table.Name,
+		"folder_id":  table.FolderId,
+		"columns":    columns,
+		"created_at": table.CreatedAt.String(),
+		"updated_at": table.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		table.Name,
+		dataTableResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-workato/pkg/connector/store"
+)
+
+const elevationsKey = "elevations"
+
+// elevation is a role granted temporarily in place of the previous role of the environment.
+type elevation struct {
+	CollaboratorId int       `json:"collaborator_id"`
+	Environment    string    `json:"environment"`
+	Role           string    `json:"role"`
+	PreviousRole   string    `json:"previous_role"`
+	GrantedAt      time.Time `json:"granted_at"`
+	// ExpiresAt is zero when the elevation lasts until it's revoked.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// elevations keeps the previous roles of elevated collaborators in the store, so they survive a restart.
+// Workato allows one role per environment, granting an elevated role replaces the role the collaborator had.
+type elevations struct {
+	store    *store.FileStore
+	roles    []string
+	duration time.Duration
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+func newElevations(stateStore *store.FileStore, roles []string, duration time.Duration) *elevations {
+	return &elevations{
+		store:    stateStore,
+		roles:    roles,
+		duration: duration,
+		now:      time.Now,
+	}
+}
+
+func elevationKey(collaboratorId int, env string) string {
+	return fmt.Sprintf("%d/%s", collaboratorId, env)
+}
+
+func (e *elevations) isElevated(roleName string) bool {
+	return e != nil && slices.Contains(e.roles, roleName)
+}
+
+func (e *elevations) load() (map[string]elevation, error) {
+	rv := make(map[string]elevation)
+
+	_, err := e.store.Load(elevationsKey, &rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// record saves the role replaced by an elevated role.
+// Elevating a collaborator that is already elevated keeps the role it had before the first elevation.
+func (e *elevations) record(collaboratorId int, env string, role string, previousRole string) (elevation, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all, err := e.load()
+	if err != nil {
+		return elevation{}, err
+	}
+
+	key := elevationKey(collaboratorId, env)
+
+	if existing, ok := all[key]; ok {
+		previousRole = existing.PreviousRole
+	}
+
+	now := e.now()
+	rv := elevation{
+		CollaboratorId: collaboratorId,
+		Environment:    env,
+		Role:           role,
+		PreviousRole:   previousRole,
+		GrantedAt:      now,
+	}
+
+	if e.duration > 0 {
+		rv.ExpiresAt = now.Add(e.duration)
+	}
+
+	all[key] = rv
+
+	return rv, e.store.Save(elevationsKey, all)
+}
+
+// put saves the elevation as it is.
+func (e *elevations) put(value elevation) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	all[elevationKey(value.CollaboratorId, value.Environment)] = value
+
+	return e.store.Save(elevationsKey, all)
+}
+
+func (e *elevations) get(collaboratorId int, env string) (elevation, bool, error) {
+	if e == nil {
+		return elevation{}, false, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all, err := e.load()
+	if err != nil {
+		return elevation{}, false, err
+	}
+
+	rv, ok := all[elevationKey(collaboratorId, env)]
+	return rv, ok, nil
+}
+
+func (e *elevations) remove(collaboratorId int, env string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	key := elevationKey(collaboratorId, env)
+	if _, ok := all[key]; !ok {
+		return nil
+	}
+
+	delete(all, key)
+
+	return e.store.Save(elevationsKey, all)
+}
+
+// expired returns the elevations past their expiry, oldest first.
+func (e *elevations) expired() ([]elevation, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	all, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.now()
+
+	rv := make([]elevation, 0)
+	for _, value := range all {
+		if !value.ExpiresAt.IsZero() && !value.ExpiresAt.After(now) {
+			rv = append(rv, value)
+		}
+	}
+
+	slices.SortFunc(rv, func(a, b elevation) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+
+	return rv, nil
+}
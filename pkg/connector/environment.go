@@ -0,0 +1,131 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+)
+
+const environmentMemberEntitlement = "member"
+
+var environmentNames = map[workato.Environment]string{
+	workato.Development: "Development",
+	workato.Test:        "Test",
+	workato.Production:  "Production",
+}
+
+// environmentBuilder is only synced in multi environment mode, projects and the other top level resources are listed under their environment.
+type environmentBuilder struct {
+	envs     *environments
+	cache    *collaboratorCache
+	children []*v2.ResourceType
+}
+
+func (o *environmentBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return environmentResourceType
+}
+
+func (o *environmentBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	o.cache.beginSync()
+
+	rv := make([]*v2.Resource, 0)
+
+	for _, env := range o.envs.list() {
+		us, err := environmentResource(env, o.children)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (o *environmentBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(collaboratorResourceType),
+		entitlement.WithDescription(fmt.Sprintf("%s has a role in %s", collaboratorResourceType.DisplayName, resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s member", resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(resource, environmentMemberEntitlement, assigmentOptions...),
+	}, "", nil, nil
+}
+
+// Grants returns the collaborators with a role in the environment.
+func (o *environmentBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, err := workato.EnvFromString(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	collaborators, err := o.cache.getUsersByEnvironment(ctx, env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Grant, 0, len(collaborators))
+
+	for _, collaborator := range collaborators {
+		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		// Membership comes from the role in the environment, it's changed by granting or revoking the role.
+		newGrant := grant.NewGrant(
+			resource,
+			environmentMemberEntitlement,
+			collaboratorId,
+			grant.WithAnnotation(&v2.GrantImmutable{}),
+		)
+
+		rv = append(rv, newGrant)
+	}
+
+	return rv, "", nil, nil
+}
+
+func newEnvironmentBuilder(envs *environments, cache *collaboratorCache, children []*v2.ResourceType) *environmentBuilder {
+	return &environmentBuilder{
+		envs:     envs,
+		cache:    cache,
+		children: children,
+	}
+}
+
+func environmentResource(env workato.Environment, children []*v2.ResourceType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":   env.String(),
+		"name": environmentNames[env],
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		environmentNames[env],
+		environmentResourceType,
+		env.String(),
+		traits,
+		rs.WithAnnotation(childResourceTypes(children)...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
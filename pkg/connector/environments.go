@@ -0,0 +1,202 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// environments are the Workato environments synced by the connector.
+// Members and their env_roles belong to the workspace and are read with the main client,
+// folders, projects, recipes, connections and the API Platform belong to an environment and are read with the API key of that environment.
+type environments struct {
+	main    workato.Environment
+	clients map[workato.Environment]*client.WorkatoClient
+	// multi is set when more than one environment is synced, resource ids and role entitlements then carry the environment.
+	multi bool
+}
+
+func newEnvironments(main workato.Environment, mainClient *client.WorkatoClient, others map[workato.Environment]*client.WorkatoClient) *environments {
+	clients := map[workato.Environment]*client.WorkatoClient{
+		main: mainClient,
+	}
+
+	for env, envClient := range others {
+		if env == main {
+			continue
+		}
+
+		clients[env] = envClient
+	}
+
+	return &environments{
+		main:    main,
+		clients: clients,
+		multi:   len(clients) > 1,
+	}
+}
+
+// list returns the environments sorted the way Workato shows them.
+func (e *environments) list() []workato.Environment {
+	rv := make([]workato.Environment, 0, len(e.clients))
+	for _, env := range []workato.Environment{workato.Development, workato.Test, workato.Production} {
+		if _, ok := e.clients[env]; ok {
+			rv = append(rv, env)
+		}
+	}
+
+	return rv
+}
+
+func (e *environments) has(env string) bool {
+	return slices.ContainsFunc(e.list(), func(other workato.Environment) bool {
+		return other.String() == env
+	})
+}
+
+// invalidateCache drops the http cache, the SDK keeps it per process so it's shared by the clients of every environment.
+func (e *environments) invalidateCache(ctx context.Context) {
+	e.clients[e.main].InvalidateCache(ctx)
+}
+
+func (e *environments) client(env workato.Environment) (*client.WorkatoClient, error) {
+	envClient, ok := e.clients[env]
+	if !ok {
+		return nil, fmt.Errorf("baton-workato: environment '%s' is not synced", env)
+	}
+
+	return envClient, nil
+}
+
+// resourceId is the id of a resource of an environment, like a folder or a project, environments have their own ids so they are prefixed in multi environment mode.
+func (e *environments) resourceId(env workato.Environment, id int) string {
+	return e.rawResourceId(env, strconv.Itoa(id))
+}
+
+// rawResourceId is resourceId for the assets whose ids aren't numbers, like data tables.
+func (e *environments) rawResourceId(env workato.Environment, id string) string {
+	if !e.multi {
+		return id
+	}
+
+	return env.String() + "/" + id
+}
+
+func (e *environments) parseResourceId(resourceId string) (workato.Environment, int, error) {
+	env, rawId, err := e.parseRawResourceId(resourceId)
+	if err != nil {
+		return "", 0, err
+	}
+
+	id, err := strconv.Atoi(rawId)
+	if err != nil {
+		return "", 0, fmt.Errorf("baton-workato: invalid resource id %s: %w", resourceId, err)
+	}
+
+	return env, id, nil
+}
+
+func (e *environments) parseRawResourceId(resourceId string) (workato.Environment, string, error) {
+	prefix, suffix, ok := strings.Cut(resourceId, "/")
+	if !ok {
+		return e.main, resourceId, nil
+	}
+
+	env, err := workato.EnvFromString(prefix)
+	if err != nil {
+		return "", "", fmt.Errorf("baton-workato: invalid resource id %s: %w", resourceId, err)
+	}
+
+	return env, suffix, nil
+}
+
+// parentEnv returns the environment whose top level resources, like projects, are listed under the parent.
+// In multi environment mode they are listed under their environment, ok is false for any other parent.
+func (e *environments) parentEnv(parentResourceID *v2.ResourceId) (workato.Environment, bool, error) {
+	if !e.multi {
+		return e.main, true, nil
+	}
+
+	if parentResourceID == nil || parentResourceID.ResourceType != environmentResourceType.Id {
+		return "", false, nil
+	}
+
+	env, err := workato.EnvFromString(parentResourceID.Resource)
+	if err != nil {
+		return "", false, err
+	}
+
+	return env, true, nil
+}
+
+// parseSameEnv parses the ids of a resource and of the principal granted it, they must belong to the same environment.
+func (e *environments) parseSameEnv(resourceId *v2.ResourceId, principalId *v2.ResourceId) (workato.Environment, int, int, error) {
+	env, id, err := e.parseResourceId(resourceId.Resource)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	principalEnv, principal, err := e.parseResourceId(principalId.Resource)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	if env != principalEnv {
+		return "", 0, 0, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: %s %s belongs to %s, %s %s belongs to %s",
+			resourceId.ResourceType,
+			resourceId.Resource,
+			env,
+			principalId.ResourceType,
+			principalId.Resource,
+			principalEnv,
+		)
+	}
+
+	return env, id, principal, nil
+}
+
+// roleEntitlement is the slug of the entitlement granting a role in an environment.
+func (e *environments) roleEntitlement(env workato.Environment) string {
+	if !e.multi {
+		return collaboratorHasRoleEntitlement
+	}
+
+	return collaboratorHasRoleEntitlement + "-" + env.String()
+}
+
+// roleEntitlementEnv returns the environment of a role entitlement.
+func (e *environments) roleEntitlementEnv(entitlement *v2.Entitlement) (workato.Environment, bool) {
+	slug := entitlementSlug(entitlement)
+
+	for _, env := range e.list() {
+		if e.roleEntitlement(env) == slug {
+			return env, true
+		}
+	}
+
+	return "", false
+}
+
+// entitlementSlug falls back to the end of the entitlement id, "type:resource:slug", when the slug wasn't sent.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+
+	return entitlement.Id[strings.LastIndex(entitlement.Id, ":")+1:]
+}
+
+// roleKey identifies a role held in an environment, Workato allows one role per environment.
+func roleKey(env string, roleName string) string {
+	return env + "/" + roleName
+}
@@ -3,12 +3,12 @@ package connector
 import (
 	"context"
 	"fmt"
-	"strconv"
-
-	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"slices"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
@@ -27,9 +27,15 @@ const (
 )
 
 type folderBuilder struct {
-	client    *client.WorkatoClient
+	envs      *environments
 	cache     *collaboratorCache
 	roleCache *roleCache
+	// dryRun logs the folder_ids changes of role grants and revokes instead of applying them.
+	dryRun bool
+	// roleLocks serializes the folder and privilege updates of a custom role.
+	roleLocks *keyedLocks
+	// children are the resource types listed under each folder, they depend on the synced features.
+	children []*v2.ResourceType
 }
 
 func (o *folderBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -41,17 +47,9 @@ func (o *folderBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 func (o *folderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
-	// Init cache
 	if pToken.Token == "" && parentResourceID == nil {
-		err := o.cache.buildCache(ctx)
-		if err != nil {
-			return nil, "", nil, err
-		}
-
-		err = o.roleCache.buildCache(ctx)
-		if err != nil {
-			return nil, "", nil, err
-		}
+		o.cache.beginSync()
+		o.roleCache.beginSync()
 	}
 
 	rv := make([]*v2.Resource, 0)
@@ -60,15 +58,31 @@ func (o *folderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 		return nil, "", nil, nil
 	}
 
+	if parentResourceID.ResourceType != projectResourceType.Id && parentResourceID.ResourceType != folderResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	// Folders are read with the client of the environment the parent belongs to.
+	env, parentId, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
 	if parentResourceID.ResourceType == projectResourceType.Id {
-		projects, nextToken, err := o.client.GetProjects(ctx, pToken.Token)
+		projects, nextToken, annos, err := envClient.GetProjects(ctx, pToken.Token)
 		if err != nil {
-			return nil, "", nil, err
+			return nil, "", annos, err
 		}
 
 		for _, project := range projects {
 			// Create a resource for the project
-			projectRs, err := projectFolderResource(&project, parentResourceID)
+			projectRs, err := projectFolderResource(&project, o.envs.resourceId(env, project.FolderId), parentResourceID, o.children)
 			if err != nil {
 				return nil, "", nil, err
 			}
@@ -76,33 +90,23 @@ func (o *folderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceI
 			rv = append(rv, projectRs)
 		}
 
-		return rv, nextToken, nil, err
+		return rv, nextToken, annos, nil
 	}
 
-	if parentResourceID.ResourceType == folderResourceType.Id {
-		parentId, err := strconv.Atoi(parentResourceID.Resource)
-		if err != nil {
-			return nil, "", nil, err
-		}
+	folders, nextToken, annos, err := envClient.GetFolders(ctx, &parentId, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
 
-		folders, nextToken, err := o.client.GetFolders(ctx, &parentId, pToken.Token)
+	for _, folder := range folders {
+		us, err := folderResource(&folder, o.envs.resourceId(env, folder.Id), parentResourceID, o.children)
 		if err != nil {
 			return nil, "", nil, err
 		}
-
-		for _, folder := range folders {
-			us, err := folderResource(&folder, parentResourceID)
-			if err != nil {
-				return nil, "", nil, err
-			}
-			rv = append(rv, us)
-		}
-
-		return rv, nextToken, nil, nil
+		rv = append(rv, us)
 	}
 
-	l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
-	return nil, "", nil, nil
+	return rv, nextToken, annos, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -161,13 +165,11 @@ func (o *folderBuilder) Grants(ctx context.Context, resource *v2.Resource, pToke
 	var rv []*v2.Grant
 
 	if state.ResourceTypeID == collaboratorResourceType.Id {
-		folderId, err := strconv.Atoi(resource.Id.Resource)
+		collaborators, err := o.cache.getUsersByFolder(ctx, resource.Id.Resource)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		collaborators := o.cache.getUsersByFolder(folderId)
-
 		for _, collaborator := range collaborators {
 			collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
 			if err != nil {
@@ -186,13 +188,20 @@ func (o *folderBuilder) Grants(ctx context.Context, resource *v2.Resource, pToke
 		}
 	}
 
+	// Roles belong to the workspace, their folder ids are folders of the main environment.
 	if state.ResourceTypeID == roleResourceType.Id {
-		folderId, err := strconv.Atoi(resource.Id.Resource)
+		env, folderId, err := o.envs.parseResourceId(resource.Id.Resource)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		roles := o.roleCache.getRoleByFolder(folderId)
+		roles := make([]*client.Role, 0)
+		if env == o.envs.main {
+			roles, err = o.roleCache.getRoleByFolder(ctx, folderId)
+			if err != nil {
+				return nil, "", nil, err
+			}
+		}
 
 		for _, role := range roles {
 			roleID, err := rs.NewResourceID(roleResourceType, role.Id)
@@ -213,15 +222,244 @@ func (o *folderBuilder) Grants(ctx context.Context, resource *v2.Resource, pToke
 	return rv, nextToken, nil, nil
 }
 
-func newFolderBuilder(client *client.WorkatoClient, env workato.Environment) *folderBuilder {
+// Grant gives a custom role access to a folder by adding it to the role folder_ids.
+// Like the synced grants, only the folder_ids of the role count, access an inheritable role gets through a parent folder doesn't.
+func (o *folderBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != roleResourceType.Id {
+		return nil, nil, fmt.Errorf("grant not implemented for %s", principal.Id.ResourceType)
+	}
+
+	if entitlementSlug(entitlement) != roleAccessEntitlement {
+		return nil, nil, fmt.Errorf("baton-workato: entitlement %s is not a role folder access", entitlement.Id)
+	}
+
+	folderId, err := o.roleFolderId(entitlement.Resource.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mainClient, err := o.envs.client(o.envs.main)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, annos, err := updateCustomRole(ctx, mainClient, o.roleLocks, principal.Id, o.dryRun, func(role *client.Role) (client.RoleRequest, bool, error) {
+		request := role.Request()
+		if slices.Contains(role.FolderIDs, folderId) {
+			return request, false, nil
+		}
+
+		request.FolderIDs = append(slices.Clone(role.FolderIDs), folderId)
+		return request, true, nil
+	})
+	if err != nil {
+		return nil, annos, err
+	}
+
+	grants := []*v2.Grant{grant.NewGrant(entitlement.Resource, roleAccessEntitlement, principal.Id)}
+
+	if !changed {
+		annos.Update(&v2.GrantAlreadyExists{})
+	}
+
+	return grants, annos, nil
+}
+
+// Revoke removes the folder from the role folder_ids.
+// Access an inheritable role gets through a parent folder can only be revoked on that folder,
+// removing the folder itself would leave the access in place.
+func (o *folderBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if grant.Principal.Id.ResourceType != roleResourceType.Id {
+		return nil, fmt.Errorf("revoke not implemented for %s", grant.Principal.Id.ResourceType)
+	}
+
+	folderId, err := o.roleFolderId(grant.Entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	mainClient, err := o.envs.client(o.envs.main)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, annos, err := updateCustomRole(ctx, mainClient, o.roleLocks, grant.Principal.Id, o.dryRun, func(role *client.Role) (client.RoleRequest, bool, error) {
+		request := role.Request()
+
+		if role.Inheritable {
+			ancestor, err := inheritedFolder(ctx, mainClient, role, folderId)
+			if err != nil {
+				return request, false, err
+			}
+
+			if ancestor != 0 {
+				return request, false, status.Errorf(
+					codes.FailedPrecondition,
+					"baton-workato: role %s reaches folder %d through its parent folder %d, revoke the parent folder instead",
+					role.Name,
+					folderId,
+					ancestor,
+				)
+			}
+		}
+
+		index := slices.Index(role.FolderIDs, folderId)
+		if index < 0 {
+			return request, false, nil
+		}
+
+		request.FolderIDs = slices.Delete(slices.Clone(role.FolderIDs), index, index+1)
+		return request, true, nil
+	})
+	if err != nil {
+		return annos, err
+	}
+
+	if !changed {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+	}
+
+	return annos, nil
+}
+
+// roleFolderId returns the id of a folder a role can be given, roles belong to the workspace and reach folders of the main environment.
+func (o *folderBuilder) roleFolderId(resourceId *v2.ResourceId) (int, error) {
+	env, folderId, err := o.envs.parseResourceId(resourceId.Resource)
+	if err != nil {
+		return 0, err
+	}
+
+	if env != o.envs.main {
+		return 0, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: roles reach folders of the %s environment, folder %s belongs to %s",
+			o.envs.main,
+			resourceId.Resource,
+			env,
+		)
+	}
+
+	return folderId, nil
+}
+
+// inheritedFolder returns the closest parent folder the role has access to, 0 when there is none.
+func inheritedFolder(ctx context.Context, workatoClient *client.WorkatoClient, role *client.Role, folderId int) (int, error) {
+	ancestors, err := folderAncestors(ctx, workatoClient, folderId)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, ancestor := range ancestors {
+		if slices.Contains(role.FolderIDs, ancestor) {
+			return ancestor, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// folderAncestors returns the folders above a folder, closest first.
+func folderAncestors(ctx context.Context, workatoClient *client.WorkatoClient, folderId int) ([]int, error) {
+	parents, err := folderParents(ctx, workatoClient, func(parents map[int]int) bool {
+		_, ok := parents[folderId]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]int, 0)
+	for id := parents[folderId]; id != 0 && !slices.Contains(rv, id); id = parents[id] {
+		rv = append(rv, id)
+	}
+
+	return rv, nil
+}
+
+// folderParents returns the parent of every folder, 0 for the project and top level folders.
+// Workato only lists the children of a folder, so the tree is walked from the project and top level folders.
+// The walk stops early once found returns true, a nil found walks the whole tree.
+func folderParents(ctx context.Context, workatoClient *client.WorkatoClient, found func(parents map[int]int) bool) (map[int]int, error) {
+	parents := make(map[int]int)
+	queue := []*int{nil}
+
+	projectToken := ""
+	for {
+		projects, nextToken, _, err := workatoClient.GetProjects(ctx, projectToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, project := range projects {
+			if _, ok := parents[project.FolderId]; ok {
+				continue
+			}
+
+			parents[project.FolderId] = 0
+			queue = append(queue, &project.FolderId)
+		}
+
+		if nextToken == "" {
+			break
+		}
+
+		projectToken = nextToken
+	}
+
+	for len(queue) > 0 {
+		if found != nil && found(parents) {
+			break
+		}
+
+		parentId := queue[0]
+		queue = queue[1:]
+
+		token := ""
+		for {
+			folders, nextToken, _, err := workatoClient.GetFolders(ctx, parentId, token)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, folder := range folders {
+				if _, ok := parents[folder.Id]; ok {
+					continue
+				}
+
+				parents[folder.Id] = folder.ParentId
+				queue = append(queue, &folder.Id)
+			}
+
+			if nextToken == "" {
+				break
+			}
+
+			token = nextToken
+		}
+	}
+
+	return parents, nil
+}
+
+func newFolderBuilder(
+	envs *environments,
+	cache *collaboratorCache,
+	roleCache *roleCache,
+	dryRun bool,
+	roleLocks *keyedLocks,
+	children []*v2.ResourceType,
+) *folderBuilder {
 	return &folderBuilder{
-		client:    client,
-		cache:     newCollaboratorCache(client, env),
-		roleCache: newRoleCache(client),
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+		dryRun:    dryRun,
+		roleLocks: roleLocks,
+		children:  children,
 	}
 }
 
-func folderResource(folder *client.Folder, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+func folderResource(folder *client.Folder, resourceId string, parentResourceId *v2.ResourceId, children []*v2.ResourceType) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"id":         folder.Id,
 		"name":       folder.Name,
@@ -237,14 +475,10 @@ func folderResource(folder *client.Folder, parentResourceId *v2.ResourceId) (*v2
 	ret, err := rs.NewAppResource(
 		folder.Name,
 		folderResourceType,
-		folder.Id,
+		resourceId,
 		traits,
 		rs.WithParentResourceID(parentResourceId),
-		rs.WithAnnotation(
-			&v2.ChildResourceType{
-				ResourceTypeId: folderResourceType.Id,
-			},
-		),
+		rs.WithAnnotation(childResourceTypes(children)...),
 	)
 	if err != nil {
 		return nil, err
@@ -253,7 +487,7 @@ func folderResource(folder *client.Folder, parentResourceId *v2.ResourceId) (*v2
 	return ret, nil
 }
 
-func projectFolderResource(project *client.Project, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+func projectFolderResource(project *client.Project, resourceId string, parentResourceId *v2.ResourceId, children []*v2.ResourceType) (*v2.Resource, error) {
 	name := fmt.Sprintf("ROOT PROJECT: %s", project.Name)
 
 	profile := map[string]interface{}{
@@ -269,14 +503,10 @@ func projectFolderResource(project *client.Project, parentResourceId *v2.Resourc
 	ret, err := rs.NewAppResource(
 		name,
 		folderResourceType,
-		project.FolderId,
+		resourceId,
 		traits,
 		rs.WithParentResourceID(parentResourceId),
-		rs.WithAnnotation(
-			&v2.ChildResourceType{
-				ResourceTypeId: folderResourceType.Id,
-			},
-		),
+		rs.WithAnnotation(childResourceTypes(children)...),
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,254 @@
+package connector
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	collaboratorSnapshotKey = "collaborator_privileges"
+
+	// Folder access of base roles changes without any updated_at moving, a full refresh once a day catches it.
+	incrementalFullSyncInterval = 24 * time.Hour
+)
+
+// collaboratorSnapshot is kept between incremental syncs.
+// Workato list endpoints can't filter by updated_at, so members, roles and folders are still listed on every sync,
+// what's saved is the per member privileges request, the expensive part of a sync.
+type collaboratorSnapshot struct {
+	// Watermark is the latest time Workato reported, it's only compared to times of Workato.
+	Watermark time.Time `json:"watermark"`
+	// SyncedAt is when the snapshot was taken on the local clock, it only schedules the full refresh.
+	SyncedAt time.Time `json:"synced_at"`
+	// Roles and Folders are the updated_at of every custom role and folder, a change of access invalidates every member.
+	Roles         map[int]time.Time            `json:"roles"`
+	Folders       map[string]time.Time         `json:"folders"`
+	Collaborators map[int]snapshotCollaborator `json:"collaborators"`
+}
+
+type snapshotCollaborator struct {
+	Roles      []client.SimpleRole             `json:"roles"`
+	Privileges []*client.CollaboratorPrivilege `json:"privileges"`
+}
+
+// accessStamps are the updated_at of the roles and folders giving access to members.
+type accessStamps struct {
+	roles   map[int]time.Time
+	folders map[string]time.Time
+}
+
+// readAccessStamps lists the custom roles and the folders of every environment.
+func (p *collaboratorCache) readAccessStamps(ctx context.Context) (*accessStamps, error) {
+	stamps := &accessStamps{
+		roles:   make(map[int]time.Time),
+		folders: make(map[string]time.Time),
+	}
+
+	roles, err := p.roleCache.getRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		stamps.roles[role.Id] = latest(role.CreatedAt, role.UpdatedAt)
+	}
+
+	for _, env := range p.envs.list() {
+		envClient, err := p.envs.client(env)
+		if err != nil {
+			return nil, err
+		}
+
+		folders, err := allFolders(ctx, envClient)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, folder := range folders {
+			stamps.folders[p.envs.resourceId(env, folder.Id)] = latest(folder.CreatedAt, folder.UpdatedAt)
+		}
+	}
+
+	return stamps, nil
+}
+
+// allFolders walks the folder tree of an environment, Workato only lists the folders of one parent at a time.
+func allFolders(ctx context.Context, envClient *client.WorkatoClient) ([]client.Folder, error) {
+	rv := make([]client.Folder, 0)
+
+	parents := []*int{nil}
+	for len(parents) > 0 {
+		parentId := parents[0]
+		parents = parents[1:]
+
+		token := ""
+		for {
+			folders, nextToken, _, err := envClient.GetFolders(ctx, parentId, token)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, folder := range folders {
+				rv = append(rv, folder)
+				parents = append(parents, &folder.Id)
+			}
+
+			if nextToken == "" {
+				break
+			}
+
+			token = nextToken
+		}
+	}
+
+	return rv, nil
+}
+
+func latest(times ...time.Time) time.Time {
+	var rv time.Time
+	for _, t := range times {
+		if t.After(rv) {
+			rv = t
+		}
+	}
+
+	return rv
+}
+
+func sameStamps[K comparable](a, b map[K]time.Time) bool {
+	return maps.EqualFunc(a, b, time.Time.Equal)
+}
+
+func sameRoles(a, b []client.SimpleRole) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, role := range a {
+		if !slices.ContainsFunc(b, role.Equals) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reusablePrivileges returns the privileges of the previous sync for collaborators that didn't change since the watermark.
+// Any change of a custom role or a folder, including a deletion, can change the access of members without touching them, nothing is reused then.
+// Otherwise a collaborator changed if its roles changed or it has activity after the watermark.
+func (p *collaboratorCache) reusablePrivileges(ctx context.Context, collaborators []client.Collaborator, stamps *accessStamps) map[int][]*client.CollaboratorPrivilege {
+	l := ctxzap.Extract(ctx)
+
+	reused := make(map[int][]*client.CollaboratorPrivilege)
+
+	if p.store == nil {
+		return reused
+	}
+
+	var snapshot collaboratorSnapshot
+
+	ok, err := p.store.Load(collaboratorSnapshotKey, &snapshot)
+	if err != nil {
+		// A broken state only costs a full sync.
+		l.Warn("Unable to load incremental state, running a full sync", zap.Error(err))
+		return reused
+	}
+
+	if !ok || time.Since(snapshot.SyncedAt) > incrementalFullSyncInterval {
+		l.Info("Incremental state is missing or too old, running a full sync")
+		return reused
+	}
+
+	if !sameStamps(snapshot.Roles, stamps.roles) || !sameStamps(snapshot.Folders, stamps.folders) {
+		l.Info("Roles or folders changed since the previous sync, running a full sync")
+		return reused
+	}
+
+	current := make(map[int]bool, len(collaborators))
+
+	for _, collaborator := range collaborators {
+		current[collaborator.Id] = true
+
+		previous, ok := snapshot.Collaborators[collaborator.Id]
+		if !ok || !sameRoles(previous.Roles, collaborator.Roles) {
+			continue
+		}
+
+		if collaborator.LastActivityLog.CreatedAt.After(snapshot.Watermark) {
+			continue
+		}
+
+		reused[collaborator.Id] = previous.Privileges
+	}
+
+	// Deleted collaborators are left out of the cache and of the next snapshot.
+	deleted := 0
+	for id := range snapshot.Collaborators {
+		if !current[id] {
+			deleted++
+		}
+	}
+
+	l.Info(
+		"Incremental sync of collaborators",
+		zap.Time("watermark", snapshot.Watermark),
+		zap.Int("unchanged_collaborators", len(reused)),
+		zap.Int("changed_collaborators", len(collaborators)-len(reused)),
+		zap.Int("deleted_collaborators", deleted),
+	)
+
+	return reused
+}
+
+// saveSnapshot keeps the privileges for the next sync, collaborators that failed are left out so they're fetched again.
+// The watermark is the latest time Workato reported, so the local clock never skews what's considered changed.
+func (p *collaboratorCache) saveSnapshot(
+	ctx context.Context,
+	syncedAt time.Time,
+	stamps *accessStamps,
+	collaborators []client.Collaborator,
+	privileges [][]*client.CollaboratorPrivilege,
+) {
+	if p.store == nil {
+		return
+	}
+
+	snapshot := collaboratorSnapshot{
+		SyncedAt:      syncedAt,
+		Roles:         stamps.roles,
+		Folders:       stamps.folders,
+		Collaborators: make(map[int]snapshotCollaborator, len(collaborators)),
+	}
+
+	for _, stamp := range stamps.roles {
+		snapshot.Watermark = latest(snapshot.Watermark, stamp)
+	}
+
+	for _, stamp := range stamps.folders {
+		snapshot.Watermark = latest(snapshot.Watermark, stamp)
+	}
+
+	for i, collaborator := range collaborators {
+		snapshot.Watermark = latest(snapshot.Watermark, collaborator.CreatedAt, collaborator.LastActivityLog.CreatedAt)
+
+		if privileges[i] == nil {
+			continue
+		}
+
+		snapshot.Collaborators[collaborator.Id] = snapshotCollaborator{
+			Roles:      collaborator.Roles,
+			Privileges: privileges[i],
+		}
+	}
+
+	err := p.store.Save(collaboratorSnapshotKey, snapshot)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("Unable to save incremental state, the next sync will be a full sync", zap.Error(err))
+	}
+}
@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"context"
+	"sync"
+)
+
+// lazyCache builds a cache the first time it's read and keeps it for the rest of the sync.
+// Reads are serialized with the build, so every builder can share the same cache.
+type lazyCache struct {
+	mu    sync.Mutex
+	built bool
+	used  bool
+	build func(ctx context.Context) error
+	// invalidate drops the http cache before a build, the SDK keeps responses longer than a sync.
+	invalidate func(ctx context.Context)
+}
+
+// read builds the cache if needed and runs fn while holding the lock.
+// The cache is built on first use, so it also works when a sync resumes from a page token in a new process.
+func (c *lazyCache) read(ctx context.Context, fn func()) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.built {
+		// The cache is built once per sync, it must not read the responses of a previous sync or of the listing.
+		if c.invalidate != nil {
+			c.invalidate(ctx)
+		}
+
+		err := c.build(ctx)
+		if err != nil {
+			return err
+		}
+
+		c.built = true
+	}
+
+	c.used = true
+	fn()
+
+	return nil
+}
+
+// beginSync is called when a sync starts listing resources.
+// The SDK lists every resource before asking for grants, so a cache that was already read belongs to a previous sync.
+func (c *lazyCache) beginSync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.used {
+		c.built = false
+		c.used = false
+	}
+}
@@ -0,0 +1,46 @@
+package connector
+
+import "sync"
+
+// keyedLocks serializes the updates of a resource, like the env_roles of a collaborator that are replaced as a whole.
+type keyedLocks struct {
+	mu    sync.Mutex
+	locks map[int]*keyedLock
+}
+
+type keyedLock struct {
+	mu sync.Mutex
+	// waiters counts the holder and the goroutines waiting, the lock is dropped when it reaches zero.
+	waiters int
+}
+
+func newKeyedLocks() *keyedLocks {
+	return &keyedLocks{
+		locks: make(map[int]*keyedLock),
+	}
+}
+
+// lock blocks until the key is free, the returned function releases it.
+func (k *keyedLocks) lock(key int) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		k.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,136 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	lookupTableReadEntitlement          = "read"
+	lookupTableUpdateRecordsEntitlement = "update-records"
+	lookupTableUpdateSchemaEntitlement  = "update-schema"
+	lookupTableDeleteEntitlement        = "delete"
+)
+
+// lookupTableEntitlements maps the lookup table entitlements to the Lookup tables privilege giving them.
+var lookupTableEntitlements = []privilegeEntitlement{
+	{slug: lookupTableReadEntitlement, privilege: "read"},
+	{slug: lookupTableUpdateRecordsEntitlement, privilege: "update_records"},
+	{slug: lookupTableUpdateSchemaEntitlement, privilege: "update_schema"},
+	{slug: lookupTableDeleteEntitlement, privilege: "delete"},
+}
+
+const lookupTablesPrivilegeGroup = "Lookup tables"
+
+type lookupTableBuilder struct {
+	envs      *environments
+	cache     *collaboratorCache
+	roleCache *roleCache
+	tables    *tableCache[client.LookupTable]
+}
+
+func (o *lookupTableBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return lookupTableResourceType
+}
+
+// List returns the lookup tables directly in a folder, tables of a project without a folder are in the folder of the project.
+func (o *lookupTableBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil {
+		o.tables.beginSync()
+		return nil, "", nil, nil
+	}
+
+	if parentResourceID.ResourceType != folderResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	env, _, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	tables, err := o.tables.getTablesByFolder(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(tables))
+
+	for _, table := range tables {
+		us, err := lookupTableResource(&table, o.envs.resourceId(env, table.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (o *lookupTableBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return newPrivilegeEntitlements(resource, lookupTablesPrivilegeGroup, lookupTableEntitlements, collaboratorResourceType, roleResourceType), "", nil, nil
+}
+
+// Grants gives a lookup table entitlement to the roles and collaborators having the Lookup tables privilege and access to the folder of the table.
+func (o *lookupTableBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, _, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := folderPrivilegeGrants(ctx, o.envs, o.cache, o.roleCache, resource, env, lookupTablesPrivilegeGroup, lookupTableEntitlements)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, "", nil, nil
+}
+
+func newLookupTableBuilder(envs *environments, cache *collaboratorCache, roleCache *roleCache, tables *tableCache[client.LookupTable]) *lookupTableBuilder {
+	return &lookupTableBuilder{
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+		tables:    tables,
+	}
+}
+
+func lookupTableResource(table *client.LookupTable, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":         table.Id,
+		"name":       table.Name,
+		"project_id": table.ProjectId,
+		"folder_id":  table.FolderId,
+		"created_at": table.CreatedAt.String(),
+		"updated_at": table.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		table.Name,
+		lookupTableResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
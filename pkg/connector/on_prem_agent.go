@@ -0,0 +1,125 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// onPremAgentBuilder syncs the agents of the on-prem groups, they have the entitlements of their group.
+type onPremAgentBuilder struct {
+	envs      *environments
+	cache     *collaboratorCache
+	roleCache *roleCache
+}
+
+func (o *onPremAgentBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return onPremAgentResourceType
+}
+
+// List returns the agents of an on-prem group, they are read with the client of the environment the group belongs to.
+func (o *onPremAgentBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	if parentResourceID.ResourceType != onPremGroupResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	env, groupId, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	agents, nextToken, annos, err := envClient.GetOnPremAgents(ctx, groupId, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(agents))
+
+	for _, agent := range agents {
+		us, err := onPremAgentResource(&agent, o.envs.resourceId(env, agent.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+func (o *onPremAgentBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return newPrivilegeEntitlements(resource, onPremPrivilegeGroup, onPremEntitlements, collaboratorResourceType, roleResourceType), "", nil, nil
+}
+
+// Grants returns the collaborators and roles with the on-prem privileges.
+func (o *onPremAgentBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, _, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := workspacePrivilegeGrants(ctx, o.cache, o.roleCache, resource, env, onPremPrivilegeGroup, onPremEntitlements)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, "", nil, nil
+}
+
+func newOnPremAgentBuilder(envs *environments, cache *collaboratorCache, roleCache *roleCache) *onPremAgentBuilder {
+	return &onPremAgentBuilder{
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+	}
+}
+
+func onPremAgentResource(agent *client.OnPremAgent, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":               agent.Id,
+		"name":             agent.Name,
+		"on_prem_group_id": agent.OnPremGroupId,
+		"status":           agent.Status,
+		"os":               agent.Os,
+		"version":          agent.Version,
+		"enabled":          agent.Enabled,
+		"created_at":       agent.CreatedAt.String(),
+		"updated_at":       agent.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		agent.Name,
+		onPremAgentResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,139 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	onPremReadEntitlement   = "read"
+	onPremEditEntitlement   = "edit"
+	onPremDeleteEntitlement = "delete"
+	// onPremConnectionEntitlement links the connections reaching their system through the group.
+	onPremConnectionEntitlement = "connection"
+)
+
+// onPremEntitlements maps the on-prem group and agent entitlements to the privilege giving them.
+var onPremEntitlements = []privilegeEntitlement{
+	{slug: onPremReadEntitlement, privilege: "read"},
+	{slug: onPremEditEntitlement, privilege: "update"},
+	{slug: onPremDeleteEntitlement, privilege: "delete"},
+}
+
+const onPremPrivilegeGroup = "On-prem groups & agents"
+
+type onPremGroupBuilder struct {
+	envs      *environments
+	cache     *collaboratorCache
+	roleCache *roleCache
+}
+
+func (o *onPremGroupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return onPremGroupResourceType
+}
+
+// List returns the on-prem groups, in multi environment mode they are listed under their environment.
+func (o *onPremGroupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	env, ok, err := o.envs.parentEnv(parentResourceID)
+	if err != nil || !ok {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	groups, nextToken, annos, err := envClient.GetOnPremGroups(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(groups))
+
+	for _, group := range groups {
+		us, err := onPremGroupResource(&group, o.envs.resourceId(env, group.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+func (o *onPremGroupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	rv := newPrivilegeEntitlements(resource, onPremPrivilegeGroup, onPremEntitlements, collaboratorResourceType, roleResourceType)
+
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(connectionResourceType),
+		entitlement.WithDescription(fmt.Sprintf("%s reaches its system through %s", connectionResourceType.DisplayName, resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s through %s", connectionResourceType.DisplayName, resource.DisplayName)),
+	}
+	rv = append(rv, entitlement.NewPermissionEntitlement(resource, onPremConnectionEntitlement, assigmentOptions...))
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the collaborators and roles with the on-prem privileges.
+// The connections of the group are granted by the connection builder, Workato only links them from the connection.
+func (o *onPremGroupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	env, _, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	rv, err := workspacePrivilegeGrants(ctx, o.cache, o.roleCache, resource, env, onPremPrivilegeGroup, onPremEntitlements)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, "", nil, nil
+}
+
+func newOnPremGroupBuilder(envs *environments, cache *collaboratorCache, roleCache *roleCache) *onPremGroupBuilder {
+	return &onPremGroupBuilder{
+		envs:      envs,
+		cache:     cache,
+		roleCache: roleCache,
+	}
+}
+
+func onPremGroupResource(group *client.OnPremGroup, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":         group.Id,
+		"name":       group.Name,
+		"created_at": group.CreatedAt.String(),
+		"updated_at": group.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		group.Name,
+		onPremGroupResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{
+				ResourceTypeId: onPremAgentResourceType.Id,
+			},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -3,12 +3,11 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-workato/pkg/connector/client"
 	"github.com/conductorone/baton-workato/pkg/connector/workato"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
-	"go.uber.org/zap"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -33,14 +32,8 @@ func (o *privilegeBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (o *privilegeBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
-
 	if pToken == nil || pToken.Token == "" {
-		err := o.cache.buildCache(ctx)
-		if err != nil {
-			l.Error("Error building cache", zap.Error(err))
-			return nil, "", nil, err
-		}
+		o.cache.beginSync()
 	}
 
 	privileges := workato.AllCompoundPrivileges()
@@ -75,7 +68,10 @@ func (o *privilegeBuilder) Entitlements(_ context.Context, resource *v2.Resource
 func (o *privilegeBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	privilegeId := resource.Id.Resource
 
-	users := o.cache.getUsersByPrivilege(privilegeId)
+	users, err := o.cache.getUsersByPrivilege(ctx, privilegeId)
+	if err != nil {
+		return nil, "", nil, err
+	}
 
 	var rv []*v2.Grant
 
@@ -100,10 +96,10 @@ func (o *privilegeBuilder) Grants(ctx context.Context, resource *v2.Resource, pT
 	return rv, "", nil, nil
 }
 
-func newPrivilegeBuilder(client *client.WorkatoClient, env workato.Environment) *privilegeBuilder {
+func newPrivilegeBuilder(client *client.WorkatoClient, cache *collaboratorCache) *privilegeBuilder {
 	return &privilegeBuilder{
 		client: client,
-		cache:  newCollaboratorCache(client, env),
+		cache:  cache,
 	}
 }
 
@@ -130,3 +126,191 @@ func privilegeResource(privilege *workato.CompoundPrivilege) (*v2.Resource, erro
 
 	return ret, nil
 }
+
+// privilegeEntitlement is an entitlement given by a privilege, on resources living in a folder the role also needs access to the folder.
+type privilegeEntitlement struct {
+	slug      string
+	privilege string
+}
+
+// newPrivilegeEntitlements describes the entitlements with the privileges giving them.
+func newPrivilegeEntitlements(resource *v2.Resource, group string, entitlements []privilegeEntitlement, grantableTo ...*v2.ResourceType) []*v2.Entitlement {
+	rv := make([]*v2.Entitlement, 0, len(entitlements))
+
+	for _, privilegeEntitlement := range entitlements {
+		description := privilegeEntitlement.slug
+		if privilege, ok := workato.FindPrivilege(workato.PrivilegeId(group, privilegeEntitlement.privilege)); ok {
+			description = privilege.Privilege.Description
+		}
+
+		assigmentOptions := []entitlement.EntitlementOption{
+			entitlement.WithGrantableTo(grantableTo...),
+			entitlement.WithDescription(fmt.Sprintf("%s %s: %s", description, resource.Id.ResourceType, resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s %s", privilegeEntitlement.slug, resource.DisplayName)),
+		}
+		rv = append(rv, entitlement.NewPermissionEntitlement(resource, privilegeEntitlement.slug, assigmentOptions...))
+	}
+
+	return rv
+}
+
+// hasFolderPrivilege tells if the role of the collaborator in the environment has the privilege and access to the folder.
+func hasFolderPrivilege(collaborator *CompoundUser, env workato.Environment, folderId int, group string, privilege string) bool {
+	return slices.ContainsFunc(collaborator.UserDetail, func(detail *client.CollaboratorPrivilege) bool {
+		return detail.EnvironmentType == env.String() &&
+			slices.Contains(detail.FolderIDs, folderId) &&
+			slices.Contains(detail.Privileges[group], privilege)
+	})
+}
+
+// folderPrivilegeGrants gives the entitlements of a resource living in a folder, like a connection or a table,
+// to the collaborators and roles having the privileges and access to its folder.
+// Roles belong to the workspace, their folder ids are folders of the main environment.
+func folderPrivilegeGrants(
+	ctx context.Context,
+	envs *environments,
+	cache *collaboratorCache,
+	roleCache *roleCache,
+	resource *v2.Resource,
+	env workato.Environment,
+	group string,
+	entitlements []privilegeEntitlement,
+) ([]*v2.Grant, error) {
+	if resource.ParentResourceId == nil {
+		return nil, fmt.Errorf("baton-workato: %s %s has no folder", resource.Id.ResourceType, resource.Id.Resource)
+	}
+
+	_, folderId, err := envs.parseResourceId(resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []*v2.Grant
+
+	collaborators, err := cache.getUsersByFolder(ctx, resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collaborator := range collaborators {
+		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, privilegeEntitlement := range entitlements {
+			if !hasFolderPrivilege(collaborator, env, folderId, group, privilegeEntitlement.privilege) {
+				continue
+			}
+
+			// To update collaborator access, the role must be updated
+			rv = append(rv, grant.NewGrant(
+				resource,
+				privilegeEntitlement.slug,
+				collaboratorId,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			))
+		}
+	}
+
+	roles := make([]*client.Role, 0)
+	if env == envs.main {
+		roles, err = roleCache.getRoleByFolder(ctx, folderId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, role := range roles {
+		roleId, err := rs.NewResourceID(roleResourceType, role.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, privilegeEntitlement := range entitlements {
+			if !slices.Contains(role.Privileges[group], privilegeEntitlement.privilege) {
+				continue
+			}
+
+			// Access is changed through the role privileges and folders.
+			rv = append(rv, grant.NewGrant(
+				resource,
+				privilegeEntitlement.slug,
+				roleId,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			))
+		}
+	}
+
+	return rv, nil
+}
+
+// workspacePrivilegeGrants gives the entitlements of a resource that isn't in a folder, like an on-prem group, from the privileges alone.
+// Collaborators get them from their role in the environment of the resource, custom roles from their privileges.
+func workspacePrivilegeGrants(
+	ctx context.Context,
+	cache *collaboratorCache,
+	roleCache *roleCache,
+	resource *v2.Resource,
+	env workato.Environment,
+	group string,
+	entitlements []privilegeEntitlement,
+) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+
+	for _, privilegeEntitlement := range entitlements {
+		collaborators, err := cache.getUsersByPrivilege(ctx, workato.PrivilegeId(group, privilegeEntitlement.privilege))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, collaborator := range collaborators {
+			inEnv := slices.ContainsFunc(collaborator.UserDetail, func(detail *client.CollaboratorPrivilege) bool {
+				return detail.EnvironmentType == env.String() && slices.Contains(detail.Privileges[group], privilegeEntitlement.privilege)
+			})
+			if !inEnv {
+				continue
+			}
+
+			collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+			if err != nil {
+				return nil, err
+			}
+
+			// To update collaborator privileges, the role must be updated
+			rv = append(rv, grant.NewGrant(
+				resource,
+				privilegeEntitlement.slug,
+				collaboratorId,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			))
+		}
+	}
+
+	roles, err := roleCache.getRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		for _, privilegeEntitlement := range entitlements {
+			if !slices.Contains(role.Privileges[group], privilegeEntitlement.privilege) {
+				continue
+			}
+
+			roleId, err := rs.NewResourceID(roleResourceType, role.Id)
+			if err != nil {
+				return nil, err
+			}
+
+			rv = append(rv, grant.NewGrant(
+				resource,
+				privilegeEntitlement.slug,
+				roleId,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			))
+		}
+	}
+
+	return rv, nil
+}
@@ -12,7 +12,7 @@ import (
 )
 
 type projectBuilder struct {
-	client *client.WorkatoClient
+	envs *environments
 }
 
 func (o *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -22,22 +22,33 @@ func (o *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (o *projectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	projects, nextToken, err := o.client.GetProjects(ctx, pToken.Token)
+	// In multi environment mode projects are listed under their environment.
+	env, ok, err := o.envs.parentEnv(parentResourceID)
+	if err != nil || !ok {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	projects, nextToken, annos, err := envClient.GetProjects(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
 	rv := make([]*v2.Resource, len(projects))
 
 	for i, project := range projects {
-		us, err := projectResource(&project)
+		us, err := projectResource(&project, o.envs.resourceId(env, project.Id), parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 		rv[i] = us
 	}
 
-	return rv, nextToken, nil, nil
+	return rv, nextToken, annos, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -50,13 +61,13 @@ func (o *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, pTok
 	return nil, "", nil, nil
 }
 
-func newProjectBuilder(client *client.WorkatoClient) *projectBuilder {
+func newProjectBuilder(envs *environments) *projectBuilder {
 	return &projectBuilder{
-		client: client,
+		envs: envs,
 	}
 }
 
-func projectResource(project *client.Project) (*v2.Resource, error) {
+func projectResource(project *client.Project, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"id":          project.Id,
 		"name":        project.Name,
@@ -71,8 +82,9 @@ func projectResource(project *client.Project) (*v2.Resource, error) {
 	ret, err := rs.NewAppResource(
 		project.Name,
 		projectResourceType,
-		project.Id,
+		resourceId,
 		traits,
+		rs.WithParentResourceID(parentResourceId),
 		rs.WithAnnotation(
 			&v2.ChildResourceType{
 				ResourceTypeId: folderResourceType.Id,
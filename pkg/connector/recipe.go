@@ -0,0 +1,206 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	recipeRunEntitlement            = "run"
+	recipeEditEntitlement           = "edit"
+	recipeReadRunHistoryEntitlement = "read-run-history"
+)
+
+// recipeEntitlements maps the recipe entitlements to the Recipes privilege giving them.
+var recipeEntitlements = []privilegeEntitlement{
+	{slug: recipeRunEntitlement, privilege: "run"},
+	{slug: recipeEditEntitlement, privilege: "update"},
+	{slug: recipeReadRunHistoryEntitlement, privilege: "read_run_history"},
+}
+
+const recipesPrivilegeGroup = "Recipes"
+
+type recipeBuilder struct {
+	envs  *environments
+	cache *collaboratorCache
+}
+
+func (o *recipeBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return recipeResourceType
+}
+
+// List returns the recipes directly in a folder, they are read with the client of the environment the folder belongs to.
+func (o *recipeBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	if parentResourceID.ResourceType != folderResourceType.Id {
+		l.Warn("Unknown parent resource type", zap.String("parent_resource_type", parentResourceID.ResourceType))
+		return nil, "", nil, nil
+	}
+
+	env, folderId, err := o.envs.parseResourceId(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	envClient, err := o.envs.client(env)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	recipes, nextToken, annos, err := envClient.GetRecipes(ctx, folderId, pToken.Token)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	rv := make([]*v2.Resource, 0, len(recipes))
+
+	for _, recipe := range recipes {
+		us, err := recipeResource(&recipe, o.envs.resourceId(env, recipe.Id), parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, us)
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+func (o *recipeBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return newPrivilegeEntitlements(resource, recipesPrivilegeGroup, recipeEntitlements, collaboratorResourceType), "", nil, nil
+}
+
+// Grants gives a recipe entitlement to the collaborators whose role in the environment of the recipe
+// has the Recipes privilege and access to the folder of the recipe.
+func (o *recipeBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, fmt.Errorf("baton-workato: recipe %s has no folder", resource.Id.Resource)
+	}
+
+	env, _, err := o.envs.parseResourceId(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	_, folderId, err := o.envs.parseResourceId(resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	collaborators, err := o.cache.getUsersByFolder(ctx, resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+
+	for _, collaborator := range collaborators {
+		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		for _, recipeEntitlement := range recipeEntitlements {
+			if !hasFolderPrivilege(collaborator, env, folderId, recipesPrivilegeGroup, recipeEntitlement.privilege) {
+				continue
+			}
+
+			// Like folder access, recipe access comes from the role of the collaborator.
+			newGrant := grant.NewGrant(
+				resource,
+				recipeEntitlement.slug,
+				collaboratorId,
+				grant.WithAnnotation(&v2.GrantImmutable{}),
+			)
+			rv = append(rv, newGrant)
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+func newRecipeBuilder(envs *environments, cache *collaboratorCache) *recipeBuilder {
+	return &recipeBuilder{
+		envs:  envs,
+		cache: cache,
+	}
+}
+
+func recipeResource(recipe *client.Recipe, resourceId string, parentResourceId *v2.ResourceId) (*v2.Resource, error) {
+	connectionIds := make([]interface{}, 0)
+	applications := make([]interface{}, 0)
+
+	for _, connection := range recipe.Config {
+		if !slices.Contains(applications, interface{}(connection.Provider)) {
+			applications = append(applications, connection.Provider)
+		}
+
+		if connection.AccountId != nil {
+			connectionIds = append(connectionIds, *connection.AccountId)
+		}
+	}
+
+	profile := map[string]interface{}{
+		"id":                  recipe.Id,
+		"name":                recipe.Name,
+		"description":         recipe.Description,
+		"folder_id":           recipe.FolderId,
+		"running":             recipe.Running,
+		"stop_cause":          recipe.StopCause,
+		"last_run_at":         formatOptionalTime(recipe.LastRunAt),
+		"stopped_at":          formatOptionalTime(recipe.StoppedAt),
+		"job_succeeded_count": recipe.JobSucceededCount,
+		"job_failed_count":    recipe.JobFailedCount,
+		"owner":               recipe.AuthorName,
+		"user_id":             recipe.UserId,
+		"version_no":          recipe.VersionNo,
+		"trigger_application": recipe.TriggerApplication,
+		"applications":        applications,
+		"connection_ids":      connectionIds,
+		"created_at":          recipe.CreatedAt.String(),
+		"updated_at":          recipe.UpdatedAt.String(),
+	}
+
+	traits := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		recipe.Name,
+		recipeResourceType,
+		resourceId,
+		traits,
+		rs.WithParentResourceID(parentResourceId),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// formatOptionalTime returns an empty string for times Workato sends as null, like the last run of a recipe that never ran.
+func formatOptionalTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.Format(time.RFC3339)
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/protobuf/proto"
 )
 
 // The user resource type is for all user objects from the database.
@@ -33,3 +34,65 @@ var projectResourceType = &v2.ResourceType{
 	Id:          "project",
 	DisplayName: "Project",
 }
+
+var environmentResourceType = &v2.ResourceType{
+	Id:          "environment",
+	DisplayName: "Environment",
+}
+
+var recipeResourceType = &v2.ResourceType{
+	Id:          "recipe",
+	DisplayName: "Recipe",
+}
+
+var connectionResourceType = &v2.ResourceType{
+	Id:          "connection",
+	DisplayName: "Connection",
+}
+
+var apiCollectionResourceType = &v2.ResourceType{
+	Id:          "api_collection",
+	DisplayName: "API Collection",
+}
+
+var apiClientResourceType = &v2.ResourceType{
+	Id:          "api_client",
+	DisplayName: "API Client",
+}
+
+var apiAccessProfileResourceType = &v2.ResourceType{
+	Id:          "api_access_profile",
+	DisplayName: "API Access Profile",
+}
+
+var onPremGroupResourceType = &v2.ResourceType{
+	Id:          "onprem_group",
+	DisplayName: "On-prem Group",
+}
+
+var onPremAgentResourceType = &v2.ResourceType{
+	Id:          "onprem_agent",
+	DisplayName: "On-prem Agent",
+}
+
+var lookupTableResourceType = &v2.ResourceType{
+	Id:          "lookup_table",
+	DisplayName: "Lookup Table",
+}
+
+var dataTableResourceType = &v2.ResourceType{
+	Id:          "data_table",
+	DisplayName: "Data Table",
+}
+
+// childResourceTypes returns the ChildResourceType annotations listing the resource types under a resource.
+func childResourceTypes(children []*v2.ResourceType) []proto.Message {
+	rv := make([]proto.Message, 0, len(children))
+	for _, child := range children {
+		rv = append(rv, &v2.ChildResourceType{
+			ResourceTypeId: child.Id,
+		})
+	}
+
+	return rv
+}
@@ -1,11 +1,14 @@
 package connector
 
 import (
+	"cmp"
 	"context"
-	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -15,8 +18,15 @@ import (
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-workato/pkg/connector/client"
 	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// maxRoleUpdateAttempts bounds how many times the roles of a collaborator, or a custom role, are updated when someone else keeps changing them.
+const maxRoleUpdateAttempts = 3
+
 var (
 	collaboratorHasRoleEntitlement = "collaborator-has"
 	roleHasPrivilegeEntitlement    = "privilege-has"
@@ -26,7 +36,18 @@ type roleBuilder struct {
 	client    *client.WorkatoClient
 	cache     *collaboratorCache
 	roleCache *roleCache
-	env       workato.Environment
+	envs      *environments
+	resolver  *roleResolver
+	// fallbackRole replaces the last role of a collaborator on revoke, empty refuses to revoke it.
+	fallbackRole string
+	// dryRun logs the env_roles and role changes instead of applying them.
+	dryRun bool
+	// locks serializes the env_roles updates of a collaborator.
+	locks *keyedLocks
+	// roleLocks serializes the folder and privilege updates of a custom role.
+	roleLocks *keyedLocks
+	// elevations is nil unless elevated roles are configured.
+	elevations *elevations
 }
 
 func (o *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -37,20 +58,14 @@ func (o *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	if pToken.Token == "" {
-		err := o.cache.buildCache(ctx)
-		if err != nil {
-			return nil, "", nil, err
-		}
-
-		err = o.roleCache.buildCache(ctx)
-		if err != nil {
-			return nil, "", nil, err
-		}
+		o.restoreExpiredElevations(ctx)
+		o.cache.beginSync()
+		o.roleCache.beginSync()
 	}
 
-	roles, nextToken, err := o.client.GetRoles(ctx, pToken.Token)
+	roles, nextToken, annos, err := o.client.GetRoles(ctx, pToken.Token)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annos, err
 	}
 
 	rv := make([]*v2.Resource, 0)
@@ -73,20 +88,31 @@ func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 		rv = append(rv, us)
 	}
 
-	return rv, nextToken, nil, nil
+	return rv, nextToken, annos, nil
 }
 
 // Entitlements always returns an empty slice for users.
 func (o *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
-	assigmentOptions := []entitlement.EntitlementOption{
-		entitlement.WithGrantableTo(collaboratorResourceType),
-		entitlement.WithDescription(fmt.Sprintf("%s has Collaborator", resource.DisplayName)),
-		entitlement.WithDisplayName(fmt.Sprintf("%s has %s", resource.DisplayName, collaboratorResourceType.DisplayName)),
+
+	// Roles are assigned per environment, in multi environment mode there is one entitlement for each of them.
+	for _, env := range o.envs.list() {
+		description := fmt.Sprintf("%s has Collaborator", resource.DisplayName)
+		displayName := fmt.Sprintf("%s has %s", resource.DisplayName, collaboratorResourceType.DisplayName)
+		if o.envs.multi {
+			description = fmt.Sprintf("%s has Collaborator in %s", resource.DisplayName, env)
+			displayName = fmt.Sprintf("%s has %s in %s", resource.DisplayName, collaboratorResourceType.DisplayName, env)
+		}
+
+		assigmentOptions := []entitlement.EntitlementOption{
+			entitlement.WithGrantableTo(collaboratorResourceType),
+			entitlement.WithDescription(description),
+			entitlement.WithDisplayName(displayName),
+		}
+		rv = append(rv, entitlement.NewAssignmentEntitlement(resource, o.envs.roleEntitlement(env), assigmentOptions...))
 	}
-	rv = append(rv, entitlement.NewAssignmentEntitlement(resource, collaboratorHasRoleEntitlement, assigmentOptions...))
 
-	assigmentOptions = []entitlement.EntitlementOption{
+	assigmentOptions := []entitlement.EntitlementOption{
 		entitlement.WithGrantableTo(privilegeResourceType),
 		entitlement.WithDescription(fmt.Sprintf("%s has privilege", resource.DisplayName)),
 		entitlement.WithDisplayName(fmt.Sprintf("%s has %s", resource.DisplayName, privilegeResourceType.DisplayName)),
@@ -98,28 +124,32 @@ func (o *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 
 // Grants always returns an empty slice for users since they don't have any entitlements.
 func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	// Since roles names are unique, we can use the role name as the key to get all the users that have that role.
-	collaborators := o.cache.getUsersByRole(resource.DisplayName)
-
 	rv := make([]*v2.Grant, 0)
 
-	for _, collaborator := range collaborators {
-		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+	roleName, err := o.resolver.roleName(ctx, resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, env := range o.envs.list() {
+		// Since roles names are unique, we can use the role name as the key to get all the users that have that role.
+		collaborators, err := o.cache.getUsersByRole(ctx, env, roleName)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		for _, roleCollab := range collaborator.User.Roles {
-			if roleCollab.RoleName != resource.DisplayName {
-				continue
+		for _, collaborator := range collaborators {
+			collaboratorId, err := rs.NewResourceID(collaboratorResourceType, collaborator.User.Id)
+			if err != nil {
+				return nil, "", nil, err
 			}
 
 			newGrant := grant.NewGrant(
 				resource,
-				collaboratorHasRoleEntitlement,
+				o.envs.roleEntitlement(env),
 				collaboratorId,
 				grant.WithGrantMetadata(map[string]interface{}{
-					"environment_type": roleCollab.EnvironmentType,
+					"environment_type": env.String(),
 				}),
 			)
 
@@ -150,7 +180,11 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 			rv = append(rv, newGrant)
 		}
 	} else {
-		role := o.roleCache.getRoleById(resource.Id.Resource)
+		role, err := o.roleCache.getRoleById(ctx, resource.Id.Resource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
 		if role == nil {
 			return rv, "", nil, fmt.Errorf("role %s not found", resource.DisplayName)
 		}
@@ -180,50 +214,83 @@ func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 }
 
 func (o *roleBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	// Add a privilege to a custom role
+	if resource.Id.ResourceType == privilegeResourceType.Id {
+		return o.grantPrivilege(ctx, resource, entitlement)
+	}
+
 	// Grant a role to a collaborator
 	if resource.Id.ResourceType == collaboratorResourceType.Id {
 		grants := make([]*v2.Grant, 0)
 
-		roleName := entitlement.Resource.Id.Resource
-		userID, err := strconv.Atoi(resource.Id.Resource)
+		if strings.HasPrefix(resource.Id.Resource, invitationIdPrefix) {
+			return nil, nil, status.Errorf(codes.FailedPrecondition, "baton-workato: %s hasn't accepted the invitation yet", resource.DisplayName)
+		}
+
+		newRole, err := o.resolver.resolve(ctx, entitlement)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		collaborator, err := o.client.GetCollaboratorPrivileges(ctx, userID)
+		userID, err := strconv.Atoi(resource.Id.Resource)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		roles := toSimpleRole(collaborator)
+		// An elevated role gives back the role it replaced when it's revoked or expires, the environment had none when it's empty.
+		// It's recorded before the update, so a granted role is never left without its record.
+		elevate := o.elevations.isElevated(newRole.RoleName) && !o.dryRun
 
-		newRole := client.SimpleRole{
-			RoleName:        roleName,
-			EnvironmentType: o.env.String(),
-		}
+		var (
+			elevated elevation
+			recorded bool
+			// prior is the record the grant overwrote, it's put back when the update fails.
+			prior    elevation
+			hadPrior bool
+		)
 
-		index := slices.IndexFunc(roles, func(other client.SimpleRole) bool {
-			return other.Equals(newRole)
-		})
+		changed, updateAnnos, err := o.updateCollaboratorRoles(ctx, userID, func(roles []client.SimpleRole) ([]client.SimpleRole, bool, error) {
+			if slices.ContainsFunc(roles, newRole.Equals) {
+				return roles, false, nil
+			}
 
-		if index >= 0 {
-			return []*v2.Grant{}, annotations.New(&v2.GrantAlreadyExists{}), nil
-		}
+			// Workato just accept one role per environment
+			sameEnvIndex := slices.IndexFunc(roles, func(other client.SimpleRole) bool {
+				return other.EnvironmentType == newRole.EnvironmentType
+			})
 
-		// Workato just accept one role per environment
-		sameEnvIndex := slices.IndexFunc(roles, func(other client.SimpleRole) bool {
-			return other.EnvironmentType == o.env.String()
-		})
+			previousRole := ""
+			if sameEnvIndex >= 0 {
+				previousRole = roles[sameEnvIndex].RoleName
+				roles[sameEnvIndex] = newRole
+			} else {
+				roles = append(roles, newRole)
+			}
 
-		if sameEnvIndex >= 0 {
-			roles[sameEnvIndex] = newRole
-		} else {
-			roles = append(roles, newRole)
-		}
+			if elevate {
+				var err error
+				if !recorded {
+					prior, hadPrior, err = o.elevations.get(userID, newRole.EnvironmentType)
+					if err != nil {
+						return nil, false, err
+					}
+				}
 
-		err = o.client.UpdateCollaboratorRoles(ctx, userID, roles)
+				elevated, err = o.elevations.record(userID, newRole.EnvironmentType, newRole.RoleName, previousRole)
+				if err != nil {
+					return nil, false, err
+				}
+				recorded = true
+			}
+
+			return roles, true, nil
+		})
 		if err != nil {
-			return nil, nil, err
+			// The role wasn't granted, its record would restore a role nobody replaced.
+			if recorded && !changed {
+				o.undoElevation(ctx, userID, newRole.EnvironmentType, prior, hadPrior)
+			}
+			return nil, updateAnnos, err
 		}
 
 		collaboratorId, err := rs.NewResourceID(collaboratorResourceType, userID)
@@ -231,47 +298,769 @@ func (o *roleBuilder) Grant(ctx context.Context, resource *v2.Resource, entitlem
 			return nil, nil, err
 		}
 
+		metadata := map[string]interface{}{
+			"environment_type": newRole.EnvironmentType,
+		}
+
+		if !changed {
+			updateAnnos.Update(&v2.GrantAlreadyExists{})
+			return []*v2.Grant{
+				grant.NewGrant(entitlement.Resource, entitlementSlug(entitlement), collaboratorId, grant.WithGrantMetadata(metadata)),
+			}, updateAnnos, nil
+		}
+
+		if recorded {
+			if elevated.PreviousRole != "" {
+				metadata["previous_role"] = elevated.PreviousRole
+			}
+			if !elevated.ExpiresAt.IsZero() {
+				metadata["expires_at"] = elevated.ExpiresAt.Format(time.RFC3339)
+			}
+		}
+
 		newGrant := grant.NewGrant(
-			resource,
-			collaboratorHasRoleEntitlement,
+			entitlement.Resource,
+			entitlementSlug(entitlement),
 			collaboratorId,
-			grant.WithGrantMetadata(map[string]interface{}{
-				"environment_type": o.env.String(),
-			}),
+			grant.WithGrantMetadata(metadata),
 		)
 
 		grants = append(grants, newGrant)
 
-		return grants, nil, nil
+		return grants, updateAnnos, nil
 	}
 
 	return nil, nil, fmt.Errorf("grant not implemented for %s", resource.Id.ResourceType)
 }
 
+// Revoke removes the role from the collaborator env_roles.
+// Workato needs at least one role, when the revoked role is the last one it's replaced by the fallback role.
 func (o *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
-	if grant.Principal.Id.ResourceType == collaboratorResourceType.Id {
-		return nil, errors.New("workato does not have revoke role for collaborator, try grant another role for the same env")
+	if grant.Principal.Id.ResourceType == privilegeResourceType.Id {
+		return o.revokePrivilege(ctx, grant)
+	}
+
+	if grant.Principal.Id.ResourceType != collaboratorResourceType.Id {
+		return nil, fmt.Errorf("revoke not implemented for %s", grant.Principal.Id.ResourceType)
+	}
+
+	if strings.HasPrefix(grant.Principal.Id.Resource, invitationIdPrefix) {
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-workato: %s hasn't accepted the invitation yet", grant.Principal.Id.Resource)
+	}
+
+	revokedRole, err := o.resolver.resolve(ctx, grant.Entitlement)
+	if err != nil {
+		// A deleted custom role is no longer held by anyone.
+		if status.Code(err) == codes.NotFound {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+		return nil, err
+	}
+
+	userID, err := strconv.Atoi(grant.Principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	elevated, ok, err := o.elevations.get(userID, revokedRole.EnvironmentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Revoking an elevated role restores the role it replaced.
+	restore := ok && elevated.Role == revokedRole.RoleName
+
+	changed, annos, err := o.updateCollaboratorRoles(ctx, userID, func(roles []client.SimpleRole) ([]client.SimpleRole, bool, error) {
+		index := slices.IndexFunc(roles, revokedRole.Equals)
+		if index < 0 {
+			return roles, false, nil
+		}
+
+		if restore && elevated.PreviousRole != "" {
+			roles[index] = client.SimpleRole{
+				RoleName:        elevated.PreviousRole,
+				EnvironmentType: elevated.Environment,
+			}
+			return roles, true, nil
+		}
+
+		roles, err := o.removeRole(roles, index)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return roles, true, nil
+	})
+	if err != nil {
+		return annos, err
+	}
+
+	if restore {
+		err = o.elevations.remove(userID, elevated.Environment)
+		if err != nil {
+			return annos, err
+		}
+	}
+
+	if !changed {
+		annos.Update(&v2.GrantAlreadyRevoked{})
 	}
 
-	return nil, fmt.Errorf("revoke not implemented for %s", grant.Principal.Id.ResourceType)
+	return annos, nil
 }
 
-func newRoleBuilder(client *client.WorkatoClient, env workato.Environment) *roleBuilder {
+// restoreExpiredElevations gives back the previous role of collaborators whose elevated role expired.
+// A failure is logged and retried on the next sync, it doesn't stop the sync.
+func (o *roleBuilder) restoreExpiredElevations(ctx context.Context) {
+	l := ctxzap.Extract(ctx)
+
+	expired, err := o.elevations.expired()
+	if err != nil {
+		l.Error("baton-workato: unable to read the elevated roles", zap.Error(err))
+		return
+	}
+
+	for _, elevated := range expired {
+		elevatedRole := client.SimpleRole{
+			RoleName:        elevated.Role,
+			EnvironmentType: elevated.Environment,
+		}
+
+		_, _, err := o.updateCollaboratorRoles(ctx, elevated.CollaboratorId, func(roles []client.SimpleRole) ([]client.SimpleRole, bool, error) {
+			// Someone else already changed the role of the environment.
+			index := slices.IndexFunc(roles, elevatedRole.Equals)
+			if index < 0 {
+				return roles, false, nil
+			}
+
+			// The environment had no role before the elevation.
+			if elevated.PreviousRole == "" {
+				roles, err := o.removeRole(roles, index)
+				if err != nil {
+					return nil, false, err
+				}
+
+				return roles, true, nil
+			}
+
+			roles[index] = client.SimpleRole{
+				RoleName:        elevated.PreviousRole,
+				EnvironmentType: elevated.Environment,
+			}
+			return roles, true, nil
+		})
+		// The restore was logged, the elevation is kept until a sync outside of dry run restores it.
+		if o.dryRun && status.Code(err) == codes.FailedPrecondition {
+			continue
+		}
+
+		if err != nil && status.Code(err) != codes.NotFound {
+			l.Error(
+				"baton-workato: unable to restore the role of an expired elevation",
+				zap.Int("collaborator_id", elevated.CollaboratorId),
+				zap.String("environment", elevated.Environment),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		err = o.elevations.remove(elevated.CollaboratorId, elevated.Environment)
+		if err != nil {
+			l.Error("baton-workato: unable to forget an expired elevation", zap.Error(err))
+			continue
+		}
+
+		l.Info(
+			"baton-workato: elevated role expired, previous role restored",
+			zap.Int("collaborator_id", elevated.CollaboratorId),
+			zap.String("environment", elevated.Environment),
+			zap.String("role", elevated.Role),
+			zap.String("previous_role", elevated.PreviousRole),
+		)
+	}
+}
+
+// removeRole drops a role from the env_roles of a collaborator.
+// Workato needs at least one role, the last one is replaced by the fallback role of its environment.
+func (o *roleBuilder) removeRole(roles []client.SimpleRole, index int) ([]client.SimpleRole, error) {
+	removed := roles[index]
+	roles = slices.Delete(roles, index, index+1)
+
+	if len(roles) > 0 {
+		return roles, nil
+	}
+
+	if o.fallbackRole == "" || o.fallbackRole == removed.RoleName {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: %s is the last role of the collaborator, Workato needs at least one role, configure a fallback role or remove the collaborator",
+			removed.RoleName,
+		)
+	}
+
+	return append(roles, client.SimpleRole{
+		RoleName:        o.fallbackRole,
+		EnvironmentType: removed.EnvironmentType,
+	}), nil
+}
+
+// undoElevation puts back the elevation record a failed grant overwrote, or forgets the one it created.
+// A failure is logged, the record then restores the role on revoke or expiry if the environment still has the elevated role.
+func (o *roleBuilder) undoElevation(ctx context.Context, userID int, env string, prior elevation, hadPrior bool) {
+	var err error
+	if hadPrior {
+		err = o.elevations.put(prior)
+	} else {
+		err = o.elevations.remove(userID, env)
+	}
+
+	if err != nil {
+		ctxzap.Extract(ctx).Error(
+			"baton-workato: unable to undo the elevation of a failed grant",
+			zap.Int("collaborator_id", userID),
+			zap.String("environment", env),
+			zap.Error(err),
+		)
+	}
+}
+
+// rolesChange returns the env_roles a collaborator should have, changed is false when the current roles are already right.
+type rolesChange func(roles []client.SimpleRole) (proposed []client.SimpleRole, changed bool, err error)
+
+// updateCollaboratorRoles applies the change to the env_roles of a collaborator, in dry run mode the change is only logged and refused.
+// Workato replaces the env_roles as a whole, so updates of a collaborator are serialized and the roles are read again after the update.
+// When someone else changed them in the meantime the change is applied again on top of their roles.
+func (o *roleBuilder) updateCollaboratorRoles(ctx context.Context, userID int, change rolesChange) (bool, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	unlock := o.locks.lock(userID)
+	defer unlock()
+
+	// Cached reads could be older than the last update of the collaborator.
+	o.client.InvalidateCache(ctx)
+
+	collaborator, annos, err := o.client.GetCollaboratorPrivileges(ctx, userID)
+	if err != nil {
+		return false, annos, err
+	}
+
+	current := toSimpleRole(collaborator)
+	updated := false
+
+	for attempt := 1; ; attempt++ {
+		proposed, changed, err := change(slices.Clone(current))
+		if err != nil || !changed {
+			return updated, annos, err
+		}
+
+		if o.dryRun {
+			l.Info(
+				"baton-workato: dry run, collaborator roles were not updated",
+				zap.Int("collaborator_id", userID),
+				zap.String("env_roles", envRolesDiff(current, proposed)),
+			)
+			return false, annos, dryRunError("roles of collaborator %d were not updated", userID)
+		}
+
+		annos, err = o.client.UpdateCollaboratorRoles(ctx, userID, proposed)
+		if err != nil {
+			// Validate only reads, a read only key is valid for syncing and fails here when provisioning.
+			if isMissingScope(err) {
+				err = status.Errorf(
+					codes.PermissionDenied,
+					"baton-workato: API key is missing scopes: Workspace collaborators: Update collaborator roles: %s",
+					err,
+				)
+			}
+			return updated, annos, err
+		}
+		updated = true
+
+		collaborator, _, err = o.client.GetCollaboratorPrivileges(ctx, userID)
+		if err != nil {
+			return updated, annos, err
+		}
+
+		current = toSimpleRole(collaborator)
+		if sameRoles(current, proposed) {
+			return updated, annos, nil
+		}
+
+		if attempt >= maxRoleUpdateAttempts {
+			return updated, annos, status.Errorf(
+				codes.Aborted,
+				"baton-workato: roles of collaborator %d changed while they were updated, expected %s",
+				userID,
+				envRolesDiff(current, proposed),
+			)
+		}
+
+		l.Warn(
+			"baton-workato: collaborator roles changed while they were updated, retrying",
+			zap.Int("collaborator_id", userID),
+			zap.Int("attempt", attempt),
+			zap.String("env_roles", envRolesDiff(current, proposed)),
+		)
+	}
+}
+
+// roleChange returns the update of a custom role, changed is false when the role is already right.
+type roleChange func(role *client.Role) (request client.RoleRequest, changed bool, err error)
+
+// updateCustomRole applies the change to a custom role, in dry run mode the change is only logged and refused.
+// Workato replaces the folder_ids and privileges as a whole, so updates of a role are serialized and the role is read again after the update.
+// When someone else changed it in the meantime the change is applied again on top of their update.
+func updateCustomRole(
+	ctx context.Context,
+	workatoClient *client.WorkatoClient,
+	locks *keyedLocks,
+	roleId *v2.ResourceId,
+	dryRun bool,
+	change roleChange,
+) (bool, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	id, err := customRoleId(roleId)
+	if err != nil {
+		return false, nil, err
+	}
+
+	unlock := locks.lock(id)
+	defer unlock()
+
+	role, err := findCustomRole(ctx, workatoClient, roleId)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var annos annotations.Annotations
+	updated := false
+
+	for attempt := 1; ; attempt++ {
+		request, changed, err := change(role)
+		if err != nil || !changed {
+			return updated, annos, err
+		}
+
+		if dryRun {
+			l.Info(
+				"baton-workato: dry run, role was not updated",
+				zap.String("role", role.Name),
+				zap.Ints("folder_ids", request.FolderIDs),
+				zap.Any("privileges", request.Privileges),
+			)
+			return false, annos, dryRunError("role %s was not updated", role.Name)
+		}
+
+		_, annos, err = workatoClient.UpdateRole(ctx, role.Id, request)
+		if err != nil {
+			return updated, annos, err
+		}
+		updated = true
+
+		role, err = findCustomRole(ctx, workatoClient, roleId)
+		if err != nil {
+			return updated, annos, err
+		}
+
+		if sameRoleAccess(role, request) {
+			return updated, annos, nil
+		}
+
+		if attempt >= maxRoleUpdateAttempts {
+			return updated, annos, status.Errorf(
+				codes.Aborted,
+				"baton-workato: role %s changed while it was updated",
+				role.Name,
+			)
+		}
+
+		l.Warn(
+			"baton-workato: role changed while it was updated, retrying",
+			zap.String("role", role.Name),
+			zap.Int("attempt", attempt),
+		)
+	}
+}
+
+// sameRoleAccess tells if the role has the folders and privileges of the request, in any order.
+func sameRoleAccess(role *client.Role, request client.RoleRequest) bool {
+	if !sameValues(role.FolderIDs, request.FolderIDs) {
+		return false
+	}
+
+	return maps.EqualFunc(role.Privileges, request.Privileges, sameValues[string])
+}
+
+func sameValues[E cmp.Ordered](a, b []E) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// grantPrivilege adds the privilege to the privilege map of a custom role, base roles can't be changed.
+func (o *roleBuilder) grantPrivilege(ctx context.Context, resource *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if entitlementSlug(entitlement) != roleHasPrivilegeEntitlement {
+		return nil, nil, fmt.Errorf("baton-workato: entitlement %s is not a role privilege", entitlement.Id)
+	}
+
+	privilege, ok := workato.FindPrivilege(resource.Id.Resource)
+	if !ok {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "baton-workato: unknown privilege %s", resource.Id.Resource)
+	}
+
+	changed, annos, err := updateCustomRole(ctx, o.client, o.roleLocks, entitlement.Resource.Id, o.dryRun, func(role *client.Role) (client.RoleRequest, bool, error) {
+		request := role.Request()
+		if slices.Contains(request.Privileges[privilege.Resource], privilege.Privilege.Id) {
+			return request, false, nil
+		}
+
+		request.Privileges = maps.Clone(request.Privileges)
+		if request.Privileges == nil {
+			request.Privileges = make(map[string][]string)
+		}
+		request.Privileges[privilege.Resource] = append(slices.Clone(request.Privileges[privilege.Resource]), privilege.Privilege.Id)
+
+		err := workato.ValidatePrivileges(request.Privileges)
+		if err != nil {
+			return request, false, status.Errorf(codes.InvalidArgument, "baton-workato: %s", err)
+		}
+
+		return request, true, nil
+	})
+	if err != nil {
+		return nil, annos, err
+	}
+
+	grants := []*v2.Grant{grant.NewGrant(entitlement.Resource, roleHasPrivilegeEntitlement, resource.Id)}
+
+	if !changed {
+		annos.Update(&v2.GrantAlreadyExists{})
+	}
+
+	return grants, annos, nil
+}
+
+// revokePrivilege removes the privilege from the privilege map of a custom role, base roles can't be changed.
+func (o *roleBuilder) revokePrivilege(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	privilege, ok := workato.FindPrivilege(grant.Principal.Id.Resource)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-workato: unknown privilege %s", grant.Principal.Id.Resource)
+	}
+
+	changed, annos, err := updateCustomRole(ctx, o.client, o.roleLocks, grant.Entitlement.Resource.Id, o.dryRun, func(role *client.Role) (client.RoleRequest, bool, error) {
+		request := role.Request()
+
+		values := request.Privileges[privilege.Resource]
+
+		index := slices.Index(values, privilege.Privilege.Id)
+		if index < 0 {
+			return request, false, nil
+		}
+
+		request.Privileges = maps.Clone(request.Privileges)
+		values = slices.Delete(slices.Clone(values), index, index+1)
+		if len(values) == 0 {
+			delete(request.Privileges, privilege.Resource)
+		} else {
+			request.Privileges[privilege.Resource] = values
+		}
+
+		err := workato.ValidatePrivileges(request.Privileges)
+		if err != nil {
+			return request, false, status.Errorf(codes.InvalidArgument, "baton-workato: %s", err)
+		}
+
+		return request, true, nil
+	})
+	if err != nil {
+		return annos, err
+	}
+
+	if !changed {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+	}
+
+	return annos, nil
+}
+
+// customRoleId returns the id of a custom role, base roles are defined by Workato and can't be changed.
+func customRoleId(roleId *v2.ResourceId) (int, error) {
+	if workato.IsBaseRole(roleId.Resource) {
+		return 0, status.Errorf(codes.FailedPrecondition, "baton-workato: %s is a base role, it can't be changed", roleId.Resource)
+	}
+
+	return strconv.Atoi(roleId.Resource)
+}
+
+// findCustomRole reads the current state of a custom role, base roles are defined by Workato and can't be changed.
+func findCustomRole(ctx context.Context, workatoClient *client.WorkatoClient, roleId *v2.ResourceId) (*client.Role, error) {
+	id, err := customRoleId(roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The role is about to be changed, cached reads could be older than its last update.
+	workatoClient.InvalidateCache(ctx)
+
+	roles, err := workatoClient.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(roles, func(role client.Role) bool {
+		return role.Id == id
+	})
+	if index < 0 {
+		return nil, status.Errorf(codes.NotFound, "baton-workato: role %s not found", roleId.Resource)
+	}
+
+	return &roles[index], nil
+}
+
+// Create creates a custom role. The role trait profile has the "privileges" by group, the "folder_ids" and "inheritable".
+func (o *roleBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	request, err := o.roleRequestFromResource(resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Workato gives the role its id, without creating it there is no resource to return.
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info(
+			"baton-workato: dry run, role was not created",
+			zap.String("role", request.Name),
+			zap.Ints("folder_ids", request.FolderIDs),
+			zap.Any("privileges", request.Privileges),
+		)
+		return nil, nil, dryRunError("role %s was not created", request.Name)
+	}
+
+	role, annos, err := o.client.CreateRole(ctx, request)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	us, err := roleResource(role)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return us, annos, nil
+}
+
+// Delete deletes a custom role, it's refused while a collaborator holds the role in any environment.
+func (o *roleBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if workato.IsBaseRole(resourceId.Resource) {
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-workato: %s is a base role, it can't be deleted", resourceId.Resource)
+	}
+
+	id, err := strconv.Atoi(resourceId.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// The holders are read fresh, a cached answer could delete a role that was granted since.
+	o.client.InvalidateCache(ctx)
+
+	roles, err := o.client.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.IndexFunc(roles, func(role client.Role) bool {
+		return role.Id == id
+	})
+	if index < 0 {
+		return nil, nil
+	}
+
+	role := roles[index]
+
+	collaborators, err := o.client.GetAllCollaborators(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var holders []string
+	for _, collaborator := range collaborators {
+		for _, collaboratorRole := range collaborator.Roles {
+			if collaboratorRole.RoleName == role.Name {
+				holders = append(holders, fmt.Sprintf("%s (%s)", collaborator.Email, collaboratorRole.EnvironmentType))
+			}
+		}
+	}
+
+	if len(holders) > 0 {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-workato: role %s is still held by %s",
+			role.Name,
+			strings.Join(holders, ", "),
+		)
+	}
+
+	if o.dryRun {
+		ctxzap.Extract(ctx).Info("baton-workato: dry run, role was not deleted", zap.String("role", role.Name))
+		return nil, dryRunError("role %s was not deleted", role.Name)
+	}
+
+	annos, err := o.client.DeleteRole(ctx, id)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return annos, err
+	}
+
+	return annos, nil
+}
+
+func (o *roleBuilder) roleRequestFromResource(resource *v2.Resource) (client.RoleRequest, error) {
+	request := client.RoleRequest{
+		Name:       resource.GetDisplayName(),
+		FolderIDs:  make([]int, 0),
+		Privileges: make(map[string][]string),
+	}
+
+	if request.Name == "" {
+		return request, status.Error(codes.InvalidArgument, "baton-workato: role name is required")
+	}
+
+	if workato.IsBaseRole(request.Name) {
+		return request, status.Errorf(codes.InvalidArgument, "baton-workato: %s is a base role name", request.Name)
+	}
+
+	roleTrait, err := rs.GetRoleTrait(resource)
+	if err != nil || roleTrait.GetProfile() == nil {
+		return request, nil
+	}
+
+	profile := roleTrait.GetProfile().AsMap()
+
+	if value, ok := profile["inheritable"]; ok {
+		inheritable, ok := value.(bool)
+		if !ok {
+			return request, status.Error(codes.InvalidArgument, "baton-workato: inheritable must be a boolean")
+		}
+		request.Inheritable = inheritable
+	}
+
+	if value, ok := profile["privileges"]; ok {
+		groups, ok := value.(map[string]interface{})
+		if !ok {
+			return request, status.Error(codes.InvalidArgument, "baton-workato: privileges must be a map of privilege lists")
+		}
+
+		for group, values := range groups {
+			list, ok := values.([]interface{})
+			if !ok {
+				return request, status.Errorf(codes.InvalidArgument, "baton-workato: privileges of %s must be a list", group)
+			}
+
+			for _, item := range list {
+				privilege, ok := item.(string)
+				if !ok {
+					return request, status.Errorf(codes.InvalidArgument, "baton-workato: privileges of %s must be strings", group)
+				}
+				request.Privileges[group] = append(request.Privileges[group], privilege)
+			}
+		}
+	}
+
+	if value, ok := profile["folder_ids"]; ok {
+		list, ok := value.([]interface{})
+		if !ok {
+			return request, status.Error(codes.InvalidArgument, "baton-workato: folder_ids must be a list")
+		}
+
+		for _, item := range list {
+			folderId, err := o.folderId(item)
+			if err != nil {
+				return request, err
+			}
+			request.FolderIDs = append(request.FolderIDs, folderId)
+		}
+	}
+
+	err = workato.ValidatePrivileges(request.Privileges)
+	if err != nil {
+		return request, status.Errorf(codes.InvalidArgument, "baton-workato: %s", err)
+	}
+
+	return request, nil
+}
+
+// folderId accepts a folder id or a folder resource id, roles can only reach folders of the main environment.
+func (o *roleBuilder) folderId(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != float64(int(v)) {
+			return 0, status.Errorf(codes.InvalidArgument, "baton-workato: invalid folder id %v", v)
+		}
+		return int(v), nil
+	case string:
+		env, id, err := o.envs.parseResourceId(v)
+		if err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "baton-workato: %s", err)
+		}
+
+		if env != o.envs.main {
+			return 0, status.Errorf(codes.InvalidArgument, "baton-workato: folder %s isn't in the %s environment", v, o.envs.main)
+		}
+
+		return id, nil
+	default:
+		return 0, status.Errorf(codes.InvalidArgument, "baton-workato: invalid folder id %v", v)
+	}
+}
+
+func newRoleBuilder(
+	client *client.WorkatoClient,
+	envs *environments,
+	cache *collaboratorCache,
+	roleCache *roleCache,
+	fallbackRole string,
+	dryRun bool,
+	locks *keyedLocks,
+	roleLocks *keyedLocks,
+	elevations *elevations,
+) *roleBuilder {
 	return &roleBuilder{
-		client:    client,
-		cache:     newCollaboratorCache(client, env),
-		roleCache: newRoleCache(client),
-		env:       env,
+		client:       client,
+		cache:        cache,
+		roleCache:    roleCache,
+		envs:         envs,
+		resolver:     newRoleResolver(client, envs, roleCache),
+		fallbackRole: fallbackRole,
+		dryRun:       dryRun,
+		locks:        locks,
+		roleLocks:    roleLocks,
+		elevations:   elevations,
 	}
 }
 
 func roleResource(role *client.Role) (*v2.Resource, error) {
+	privileges := make(map[string]interface{}, len(role.Privileges))
+	for group, values := range role.Privileges {
+		list := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			list = append(list, value)
+		}
+		privileges[group] = list
+	}
+
+	folderIds := make([]interface{}, 0, len(role.FolderIDs))
+	for _, folderId := range role.FolderIDs {
+		folderIds = append(folderIds, folderId)
+	}
+
 	profile := map[string]interface{}{
 		"id":          role.Id,
 		"name":        role.Name,
 		"create_at":   role.CreatedAt.String(),
 		"inheritable": role.Inheritable,
 		"updated_at":  role.UpdatedAt.String(),
+		"privileges":  privileges,
+		"folder_ids":  folderIds,
 	}
 
 	traits := []rs.RoleTraitOption{
@@ -322,3 +1111,46 @@ func toSimpleRole(collaboratorRoles []*client.CollaboratorPrivilege) []client.Si
 
 	return roles
 }
+
+// envRolesDiff describes a change of env_roles, one entry per environment: "dev: Admin -> Builders, prod: - Analyst".
+func envRolesDiff(current, proposed []client.SimpleRole) string {
+	before := make(map[string]string, len(current))
+	for _, role := range current {
+		before[role.EnvironmentType] = role.RoleName
+	}
+
+	after := make(map[string]string, len(proposed))
+	for _, role := range proposed {
+		after[role.EnvironmentType] = role.RoleName
+	}
+
+	envs := make([]string, 0, len(before)+len(after))
+	for env := range before {
+		envs = append(envs, env)
+	}
+	for env := range after {
+		if _, ok := before[env]; !ok {
+			envs = append(envs, env)
+		}
+	}
+	slices.Sort(envs)
+
+	changes := make([]string, 0, len(envs))
+	for _, env := range envs {
+		oldRole, hadRole := before[env]
+		newRole, hasRole := after[env]
+
+		switch {
+		case !hadRole:
+			changes = append(changes, fmt.Sprintf("%s: + %s", env, newRole))
+		case !hasRole:
+			changes = append(changes, fmt.Sprintf("%s: - %s", env, oldRole))
+		case oldRole != newRole:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", env, oldRole, newRole))
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %s", env, oldRole))
+		}
+	}
+
+	return strings.Join(changes, ", ")
+}
@@ -12,14 +12,24 @@ type roleCache struct {
 	client       *client.WorkatoClient
 	folderToRole map[int][]*client.Role
 	roles        map[string]*client.Role
+	lazy         lazyCache
 }
 
 func newRoleCache(workatoClient *client.WorkatoClient) *roleCache {
-	return &roleCache{
+	cache := &roleCache{
 		client:       workatoClient,
 		folderToRole: make(map[int][]*client.Role),
 		roles:        make(map[string]*client.Role),
 	}
+
+	cache.lazy.build = cache.buildCache
+	cache.lazy.invalidate = workatoClient.InvalidateCache
+
+	return cache
+}
+
+func (p *roleCache) beginSync() {
+	p.lazy.beginSync()
 }
 
 func (p *roleCache) buildCache(ctx context.Context) error {
@@ -33,7 +43,7 @@ func (p *roleCache) buildCache(ctx context.Context) error {
 	token := ""
 
 	for {
-		roles, nextToken, err := p.client.GetRoles(ctx, token)
+		roles, nextToken, _, err := p.client.GetRoles(ctx, token)
 		if err != nil {
 			return err
 		}
@@ -59,20 +69,30 @@ func (p *roleCache) buildCache(ctx context.Context) error {
 	return nil
 }
 
-func (p *roleCache) getRoleByFolder(folderId int) []*client.Role {
-	value, ok := p.folderToRole[folderId]
-	if !ok {
-		return make([]*client.Role, 0)
-	}
-
-	return value
+func (p *roleCache) getRoles(ctx context.Context) ([]*client.Role, error) {
+	rv := make([]*client.Role, 0)
+	err := p.lazy.read(ctx, func() {
+		for _, role := range p.roles {
+			rv = append(rv, role)
+		}
+	})
+	return rv, err
 }
 
-func (p *roleCache) getRoleById(id string) *client.Role {
-	value, ok := p.roles[id]
-	if !ok {
-		return nil
-	}
+func (p *roleCache) getRoleByFolder(ctx context.Context, folderId int) ([]*client.Role, error) {
+	rv := make([]*client.Role, 0)
+	err := p.lazy.read(ctx, func() {
+		if value, ok := p.folderToRole[folderId]; ok {
+			rv = value
+		}
+	})
+	return rv, err
+}
 
-	return value
+func (p *roleCache) getRoleById(ctx context.Context, id string) (*client.Role, error) {
+	var rv *client.Role
+	err := p.lazy.read(ctx, func() {
+		rv = p.roles[id]
+	})
+	return rv, err
 }
@@ -0,0 +1,75 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/conductorone/baton-workato/pkg/connector/workato"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// roleResolver maps role resources to the role Workato expects in env_roles.
+// Base roles use their name as resource id while custom roles use their numeric id.
+type roleResolver struct {
+	client    *client.WorkatoClient
+	envs      *environments
+	roleCache *roleCache
+}
+
+func newRoleResolver(workatoClient *client.WorkatoClient, envs *environments, roleCache *roleCache) *roleResolver {
+	return &roleResolver{
+		client:    workatoClient,
+		envs:      envs,
+		roleCache: roleCache,
+	}
+}
+
+// roleName returns the name of a role resource.
+func (r *roleResolver) roleName(ctx context.Context, roleId *v2.ResourceId) (string, error) {
+	if baseRole, err := workato.GetBaseRole(roleId.Resource); err == nil {
+		return baseRole.RoleName, nil
+	}
+
+	if _, err := strconv.Atoi(roleId.Resource); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "baton-workato: role %s is neither a base role nor a custom role id", roleId.Resource)
+	}
+
+	role, err := r.roleCache.getRoleById(ctx, roleId.Resource)
+	if err != nil {
+		return "", err
+	}
+
+	if role != nil {
+		return role.Name, nil
+	}
+
+	// The role may have been created after the cache was built.
+	role, err = findCustomRole(ctx, r.client, roleId)
+	if err != nil {
+		return "", err
+	}
+
+	return role.Name, nil
+}
+
+// resolve returns the role and environment of a role assignment entitlement.
+func (r *roleResolver) resolve(ctx context.Context, entitlement *v2.Entitlement) (client.SimpleRole, error) {
+	env, ok := r.envs.roleEntitlementEnv(entitlement)
+	if !ok {
+		return client.SimpleRole{}, fmt.Errorf("baton-workato: entitlement %s is not a role assignment of a synced environment", entitlement.Id)
+	}
+
+	name, err := r.roleName(ctx, entitlement.Resource.Id)
+	if err != nil {
+		return client.SimpleRole{}, err
+	}
+
+	return client.SimpleRole{
+		RoleName:        name,
+		EnvironmentType: env.String(),
+	}, nil
+}
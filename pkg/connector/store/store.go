@@ -0,0 +1,134 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps small pieces of connector state between runs in a single JSON file.
+// Every key holds an independent JSON document.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+func (s *FileStore) readAll() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make(map[string]json.RawMessage), nil
+		}
+
+		return nil, err
+	}
+
+	values := make(map[string]json.RawMessage)
+	if len(data) == 0 {
+		return values, nil
+	}
+
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, fmt.Errorf("baton-workato: state file %s is corrupt: %w", s.path, err)
+	}
+
+	return values, nil
+}
+
+// writeAll replaces the file atomically, a crash never leaves a half written state.
+func (s *FileStore) writeAll(values map[string]json.RawMessage) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Load decodes the value of key, it returns false when the key was never saved.
+func (s *FileStore) Load(key string, value interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.readAll()
+	if err != nil {
+		return false, err
+	}
+
+	raw, ok := values[key]
+	if !ok {
+		return false, nil
+	}
+
+	err = json.Unmarshal(raw, value)
+	if err != nil {
+		return false, fmt.Errorf("baton-workato: state '%s' is corrupt: %w", key, err)
+	}
+
+	return true, nil
+}
+
+func (s *FileStore) Save(key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	values[key] = raw
+
+	return s.writeAll(values)
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+
+	delete(values, key)
+
+	return s.writeAll(values)
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	type state struct {
+		Name  string
+		Count int
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewFileStore(path)
+
+	var loaded state
+	ok, err := s.Load("first", &loaded)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Save("first", state{Name: "first", Count: 1}))
+	require.NoError(t, s.Save("second", state{Name: "second", Count: 2}))
+
+	// A new store reads what the previous one wrote.
+	s = NewFileStore(path)
+
+	ok, err = s.Load("first", &loaded)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, state{Name: "first", Count: 1}, loaded)
+
+	require.NoError(t, s.Delete("first"))
+
+	ok, err = s.Load("first", &loaded)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = s.Load("second", &loaded)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, state{Name: "second", Count: 2}, loaded)
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{invalid"), 0600))
+
+	var loaded map[string]string
+	_, err := NewFileStore(path).Load("first", &loaded)
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+// tableCache keeps the tables of every environment by folder resource id.
+// Workato lists lookup and data tables for the whole environment, so they are read once instead of once per folder.
+type tableCache[T any] struct {
+	envs *environments
+	name string
+	// byFolder reads the tables of an environment keyed by the id of their folder.
+	byFolder func(ctx context.Context, envClient *client.WorkatoClient) (map[int][]T, error)
+	tables   map[string][]T
+	lazy     lazyCache
+}
+
+func newTableCache[T any](envs *environments, name string, byFolder func(ctx context.Context, envClient *client.WorkatoClient) (map[int][]T, error)) *tableCache[T] {
+	cache := &tableCache[T]{
+		envs:     envs,
+		name:     name,
+		byFolder: byFolder,
+		tables:   make(map[string][]T),
+	}
+
+	cache.lazy.build = cache.buildCache
+	cache.lazy.invalidate = envs.invalidateCache
+
+	return cache
+}
+
+func newLookupTableCache(envs *environments) *tableCache[client.LookupTable] {
+	return newTableCache(envs, "lookup tables", lookupTablesByFolder)
+}
+
+func newDataTableCache(envs *environments) *tableCache[client.DataTable] {
+	return newTableCache(envs, "data tables", dataTablesByFolder)
+}
+
+func (p *tableCache[T]) beginSync() {
+	p.lazy.beginSync()
+}
+
+func (p *tableCache[T]) buildCache(ctx context.Context) error {
+	l := ctxzap.Extract(ctx)
+
+	l.Info("Building cache for " + p.name)
+
+	p.tables = make(map[string][]T)
+
+	for _, env := range p.envs.list() {
+		envClient, err := p.envs.client(env)
+		if err != nil {
+			return err
+		}
+
+		tables, err := p.byFolder(ctx, envClient)
+		if err != nil {
+			return err
+		}
+
+		for folderId, folderTables := range tables {
+			p.tables[p.envs.resourceId(env, folderId)] = folderTables
+		}
+	}
+
+	l.Info("Cache built for " + p.name)
+
+	return nil
+}
+
+// getTablesByFolder returns the tables directly in the folder.
+func (p *tableCache[T]) getTablesByFolder(ctx context.Context, folderResourceId string) ([]T, error) {
+	var rv []T
+	err := p.lazy.read(ctx, func() {
+		rv = p.tables[folderResourceId]
+	})
+	return rv, err
+}
+
+// lookupTablesByFolder places the lookup tables without a folder in the folder of their project.
+func lookupTablesByFolder(ctx context.Context, envClient *client.WorkatoClient) (map[int][]client.LookupTable, error) {
+	tables, err := envClient.GetAllLookupTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var projectFolders map[int]int
+
+	rv := make(map[int][]client.LookupTable)
+
+	for _, table := range tables {
+		folderId := table.FolderId
+		if folderId == 0 {
+			if projectFolders == nil {
+				projectFolders, err = projectFolderIds(ctx, envClient)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			folderId = projectFolders[table.ProjectId]
+		}
+
+		if folderId == 0 {
+			continue
+		}
+
+		rv[folderId] = append(rv[folderId], table)
+	}
+
+	return rv, nil
+}
+
+func dataTablesByFolder(ctx context.Context, envClient *client.WorkatoClient) (map[int][]client.DataTable, error) {
+	tables, err := envClient.GetAllDataTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make(map[int][]client.DataTable)
+
+	for _, table := range tables {
+		rv[table.FolderId] = append(rv[table.FolderId], table)
+	}
+
+	return rv, nil
+}
+
+// projectFolderIds maps the projects to their folder.
+func projectFolderIds(ctx context.Context, envClient *client.WorkatoClient) (map[int]int, error) {
+	projects, err := envClient.GetAllProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make(map[int]int, len(projects))
+	for _, project := range projects {
+		rv[project.Id] = project.FolderId
+	}
+
+	return rv, nil
+}
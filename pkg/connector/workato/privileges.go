@@ -3,6 +3,7 @@ package workato
 import (
 	"fmt"
 	"slices"
+	"strings"
 )
 
 type Privilege struct {
@@ -425,10 +426,63 @@ Run test cases`,
 	}
 )
 
+// documentedPrivilegeGroups lists the groups of Privileges in the order of the Workato docs.
+var documentedPrivilegeGroups = []string{
+	"Runtime user connections",
+	"Event streams",
+	"Lookup tables",
+	"People task",
+	"Recipes",
+	"Folders",
+	"Projects",
+	"Connections",
+	"Connector SDK",
+	"Use in recipes",
+	"On-prem groups & agents",
+	"Connection - on-prem files",
+	"Connection - command line scripts",
+	"Project folder",
+	"Connection Folders",
+	"Common data models",
+	"Message templates",
+	"Workbot",
+	"Job History Search",
+	"Test automation",
+	"Data masking",
+	"Environment properties",
+	"Project properties",
+	"Secrets management",
+	"Activity audit",
+	"Collaborator SAML SSO auth",
+	"Collaborators",
+	"Recipe lifecycle management",
+	"Collaborator roles (non-system)",
+	"Developer API",
+	"Workspace settings",
+	"Debug, Log and Security",
+	"Network trace",
+}
+
+// privilegeGroups is the order privileges are returned in, the documented groups first.
+// A group of Privileges missing from the docs order follows in alphabetical order instead of being dropped.
+var privilegeGroups = withRemainingGroups(documentedPrivilegeGroups)
+
+func withRemainingGroups(groups []string) []string {
+	var remaining []string
+	for group := range Privileges {
+		if !slices.Contains(groups, group) {
+			remaining = append(remaining, group)
+		}
+	}
+	slices.Sort(remaining)
+
+	return append(slices.Clone(groups), remaining...)
+}
+
 func AllCompoundPrivileges() []CompoundPrivilege {
 	var all []CompoundPrivilege
-	for resource, privileges := range Privileges {
-		for _, privilege := range privileges {
+	for _, resource := range privilegeGroups {
+		for _, privilege := range Privileges[resource] {
 			compoundPrivilege := CompoundPrivilege{
 				Resource:  resource,
 				Privilege: privilege,
@@ -477,7 +531,8 @@ func FindRelatedPrivilegesErr(param map[string][]string) ([]CompoundPrivilege, e
 func FindRelatedPrivileges(param map[string][]string) []CompoundPrivilege {
 	all := make([]CompoundPrivilege, 0)
 
-	for key, values := range param {
+	for _, key := range privilegeGroups {
+		values := param[key]
 		if reference, ok := Privileges[key]; ok {
 			for _, value := range values {
 				// Since it's a small list, we can use a linear search
@@ -499,3 +554,71 @@ func FindRelatedPrivileges(param map[string][]string) []CompoundPrivilege {
 
 	return all
 }
+
+// readPrivilege is implied by the other privileges of its group, e.g. updating recipes needs reading them.
+const readPrivilege = "read"
+
+// FindPrivilege returns the privilege of a privilege resource id, "<group>-<privilege>".
+func FindPrivilege(id string) (CompoundPrivilege, bool) {
+	for group, privileges := range Privileges {
+		for _, privilege := range privileges {
+			if PrivilegeId(group, privilege.Id) == id {
+				return CompoundPrivilege{Resource: group, Privilege: privilege}, true
+			}
+		}
+	}
+
+	return CompoundPrivilege{}, false
+}
+
+// ValidatePrivileges checks that every privilege of a role exists in the catalog
+// and that the read privilege of a group is kept when the group has other privileges.
+func ValidatePrivileges(privileges map[string][]string) error {
+	var unknown []string
+
+	for group, values := range privileges {
+		reference, ok := Privileges[group]
+		if !ok {
+			unknown = append(unknown, group)
+			continue
+		}
+
+		for _, value := range values {
+			exists := slices.ContainsFunc(reference, func(c Privilege) bool {
+				return c.Id == value
+			})
+
+			if !exists {
+				unknown = append(unknown, PrivilegeId(group, value))
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("unknown privileges: %s", strings.Join(unknown, ", "))
+	}
+
+	var missing []string
+
+	for group, values := range privileges {
+		hasRead := slices.ContainsFunc(Privileges[group], func(c Privilege) bool {
+			return c.Id == readPrivilege
+		})
+
+		if !hasRead || slices.Contains(values, readPrivilege) {
+			continue
+		}
+
+		for _, value := range values {
+			missing = append(missing, fmt.Sprintf("%s needs %s", PrivilegeId(group, value), PrivilegeId(group, readPrivilege)))
+		}
+	}
+
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("missing implied privileges: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -13,6 +13,26 @@ func TestAllCompoundPrivileges(t *testing.T) {
 	}
 }
 
+func TestDocumentedPrivilegeGroups(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, group := range documentedPrivilegeGroups {
+		if _, ok := Privileges[group]; !ok {
+			t.Errorf("Expected %s to be a group of Privileges", group)
+		}
+
+		if seen[group] {
+			t.Errorf("Expected %s to be listed once", group)
+		}
+		seen[group] = true
+	}
+
+	for group := range Privileges {
+		if !seen[group] {
+			t.Errorf("Expected %s to be in documentedPrivilegeGroups", group)
+		}
+	}
+}
+
 func TestFindRelatedPrivileges(t *testing.T) {
 	cases := []struct {
 		name string
@@ -78,3 +98,64 @@ func TestFindRelatedPrivileges(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePrivileges(t *testing.T) {
+	cases := []struct {
+		name  string
+		input map[string][]string
+		err   string
+	}{
+		{
+			name:  "empty",
+			input: map[string][]string{},
+		},
+		{
+			name: "valid",
+			input: map[string][]string{
+				"Recipes": {"read", "create"},
+				"Folders": {"read"},
+			},
+		},
+		{
+			name: "unknown",
+			input: map[string][]string{
+				"Recipes": {"read", "fly"},
+				"Rockets": {"read"},
+			},
+			err: "unknown privileges: Recipes-fly, Rockets",
+		},
+		{
+			name: "implied read",
+			input: map[string][]string{
+				"Recipes":       {"update", "run"},
+				"Folders":       {"read"},
+				"Network trace": {"all"},
+			},
+			err: "missing implied privileges: Recipes-run needs Recipes-read, Recipes-update needs Recipes-read",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidatePrivileges(c.input)
+			if c.err == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if c.err != "" && (err == nil || err.Error() != c.err) {
+				t.Errorf("Expected %s, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestFindPrivilege(t *testing.T) {
+	privilege, ok := FindPrivilege("Collaborator roles (non-system)-all")
+	if !ok || privilege.Resource != "Collaborator roles (non-system)" || privilege.Privilege.Id != "all" {
+		t.Errorf("Expected Collaborator roles (non-system) all, got %v", privilege)
+	}
+
+	if _, ok := FindPrivilege("Recipes-fly"); ok {
+		t.Error("Expected Recipes-fly to be unknown")
+	}
+}
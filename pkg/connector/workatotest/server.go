@@ -0,0 +1,971 @@
+// Package workatotest provides an in-process fake of the Workato API for tests.
+package workatotest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-workato/pkg/connector/client"
+)
+
+const ApiKey = "workatotest-api-key"
+
+// Fault is returned instead of the real response for matching requests.
+type Fault struct {
+	StatusCode int
+	Body       string
+	Header     http.Header
+	// Times is how many requests fail, zero or less fails every request.
+	Times int
+}
+
+type hook struct {
+	method string
+	path   string
+	times  int
+	served int
+	run    func()
+}
+
+type fault struct {
+	Fault
+	method string
+	path   string
+	served int
+}
+
+// Server fakes the Workato members, member invitations, member privileges, roles, folders, projects, recipes, connections,
+// API Platform and on-prem endpoints.
+// Roles can be created, updated and deleted, API access profiles can be updated, enabled and disabled.
+// The data can be changed at any time, every request reads the current state.
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	workspace     client.Workspace
+	collaborators []client.Collaborator
+	privileges    map[int][]*client.CollaboratorPrivilege
+	invitations   []client.MemberInvitation
+	roles         []client.Role
+	folders       []client.Folder
+	projects      []client.Project
+	recipes       []client.Recipe
+	connections   []client.Connection
+	collections   []client.ApiCollection
+	apiClients    []client.ApiClient
+	profiles      []client.ApiAccessProfile
+	onPremGroups  []client.OnPremGroup
+	onPremAgents  []client.OnPremAgent
+	lookupTables  []client.LookupTable
+	dataTables    []client.DataTable
+	faults        []*fault
+	hooks         []*hook
+	requests      []string
+}
+
+// NewServer starts a fake Workato API, it's closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		workspace: client.Workspace{
+			Id:   1,
+			Name: "workatotest",
+		},
+		privileges: make(map[int][]*client.CollaboratorPrivilege),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/me", s.getWorkspace)
+	mux.HandleFunc("GET /api/members", s.getCollaborators)
+	mux.HandleFunc("GET /api/members/{id}/privileges", s.getCollaboratorPrivileges)
+	mux.HandleFunc("PUT /api/members/{id}", s.updateCollaboratorRoles)
+	mux.HandleFunc("DELETE /api/members/{id}", s.deleteCollaborator)
+	mux.HandleFunc("GET /api/member_invitations", s.getInvitations)
+	mux.HandleFunc("POST /api/member_invitations", s.inviteCollaborator)
+	mux.HandleFunc("GET /api/roles", s.getRoles)
+	mux.HandleFunc("POST /api/roles", s.createRole)
+	mux.HandleFunc("PUT /api/roles/{id}", s.updateRole)
+	mux.HandleFunc("DELETE /api/roles/{id}", s.deleteRole)
+	mux.HandleFunc("GET /api/folders", s.getFolders)
+	mux.HandleFunc("GET /api/projects", s.getProjects)
+	mux.HandleFunc("GET /api/recipes", s.getRecipes)
+	mux.HandleFunc("GET /api/connections", s.getConnections)
+	mux.HandleFunc("GET /api/api_collections", s.getApiCollections)
+	mux.HandleFunc("GET /api/api_clients", s.getApiClients)
+	mux.HandleFunc("GET /api/api_access_profiles", s.getApiAccessProfiles)
+	mux.HandleFunc("PUT /api/api_access_profiles/{id}", s.updateApiAccessProfile)
+	mux.HandleFunc("PUT /api/api_access_profiles/{id}/enable", s.setApiAccessProfileActive(true))
+	mux.HandleFunc("PUT /api/api_access_profiles/{id}/disable", s.setApiAccessProfileActive(false))
+	mux.HandleFunc("GET /api/on_prem_groups", s.getOnPremGroups)
+	mux.HandleFunc("GET /api/on_prem_groups/{id}/agents", s.getOnPremAgents)
+	mux.HandleFunc("GET /api/lookup_tables", s.getLookupTables)
+	mux.HandleFunc("GET /api/data_tables", s.getDataTables)
+
+	s.server = httptest.NewServer(s.middleware(mux))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// URL is the base url to give to client.NewWorkatoClient.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns a client pointing to the server.
+// The SDK http cache is on like in production, so tests see stale reads the connector doesn't invalidate.
+func (s *Server) Client(t *testing.T, opts ...client.Option) *client.WorkatoClient {
+	t.Helper()
+
+	t.Setenv("BATON_DISABLE_HTTP_CACHE", "false")
+
+	workatoClient, err := client.NewWorkatoClient(context.Background(), ApiKey, s.URL(), opts...)
+	if err != nil {
+		t.Fatalf("workatotest: unable to create client: %v", err)
+	}
+
+	return workatoClient
+}
+
+// SetWorkspace changes the workspace returned by /api/users/me.
+func (s *Server) SetWorkspace(workspace client.Workspace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workspace = workspace
+}
+
+// AddCollaborator adds a member, its roles are derived from the privileges.
+func (s *Server) AddCollaborator(collaborator client.Collaborator, privileges ...*client.CollaboratorPrivilege) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collaborator.Roles = make([]client.SimpleRole, 0, len(privileges))
+	for _, privilege := range privileges {
+		collaborator.Roles = append(collaborator.Roles, privilege.SimpleRole())
+	}
+
+	s.collaborators = append(s.collaborators, collaborator)
+	s.privileges[collaborator.Id] = privileges
+}
+
+func (s *Server) AddRole(role client.Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles = append(s.roles, role)
+}
+
+// Role returns a custom role by id.
+func (s *Server) Role(id int) (client.Role, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.roles, func(r client.Role) bool {
+		return r.Id == id
+	})
+	if index < 0 {
+		return client.Role{}, false
+	}
+
+	return s.roles[index], true
+}
+
+// SetRoleFolders changes the folder_ids of a custom role, like an edit made in Workato.
+func (s *Server) SetRoleFolders(id int, folderIds ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.roles {
+		if s.roles[i].Id == id {
+			s.roles[i].FolderIDs = folderIds
+		}
+	}
+}
+
+func (s *Server) AddFolder(folder client.Folder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.folders = append(s.folders, folder)
+}
+
+func (s *Server) AddProject(project client.Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.projects = append(s.projects, project)
+}
+
+func (s *Server) AddRecipe(recipe client.Recipe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recipes = append(s.recipes, recipe)
+}
+
+func (s *Server) AddConnection(connection client.Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connections = append(s.connections, connection)
+}
+
+func (s *Server) AddApiCollection(collection client.ApiCollection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections = append(s.collections, collection)
+}
+
+func (s *Server) AddApiClient(apiClient client.ApiClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiClients = append(s.apiClients, apiClient)
+}
+
+func (s *Server) AddApiAccessProfile(profile client.ApiAccessProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profiles = append(s.profiles, profile)
+}
+
+func (s *Server) AddOnPremGroup(group client.OnPremGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onPremGroups = append(s.onPremGroups, group)
+}
+
+func (s *Server) AddOnPremAgent(agent client.OnPremAgent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onPremAgents = append(s.onPremAgents, agent)
+}
+
+func (s *Server) AddLookupTable(table client.LookupTable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lookupTables = append(s.lookupTables, table)
+}
+
+func (s *Server) AddDataTable(table client.DataTable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dataTables = append(s.dataTables, table)
+}
+
+// ApiAccessProfile returns an access profile by id.
+func (s *Server) ApiAccessProfile(id int) (client.ApiAccessProfile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.profiles, func(p client.ApiAccessProfile) bool {
+		return p.Id == id
+	})
+	if index < 0 {
+		return client.ApiAccessProfile{}, false
+	}
+
+	return s.profiles[index], true
+}
+
+// CollaboratorRoles returns the current roles of a member.
+func (s *Server) CollaboratorRoles(id int) []client.SimpleRole {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, collaborator := range s.collaborators {
+		if collaborator.Id == id {
+			return slices.Clone(collaborator.Roles)
+		}
+	}
+
+	return nil
+}
+
+// SetCollaboratorRoles replaces the roles of a member, like a change made in the Workato UI.
+func (s *Server) SetCollaboratorRoles(id int, roles ...client.SimpleRole) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.collaborators, func(c client.Collaborator) bool {
+		return c.Id == id
+	})
+	if index >= 0 {
+		s.setCollaboratorRoles(index, roles)
+	}
+}
+
+// Invitations returns the pending invitations.
+func (s *Server) Invitations() []client.MemberInvitation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.invitations)
+}
+
+// Fail makes requests matching the method and path prefix return the fault.
+func (s *Server) Fail(method, path string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &fault{
+		Fault:  f,
+		method: method,
+		path:   path,
+	})
+}
+
+// After runs the hook once a request matching the method and path prefix was served.
+// Times is how many requests run the hook, zero or less runs it after every request.
+func (s *Server) After(method, path string, times int, run func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, &hook{
+		method: method,
+		path:   path,
+		times:  times,
+		run:    run,
+	})
+}
+
+// Requests returns every request served as "METHOD /path".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		f := s.matchFault(r)
+		s.mu.Unlock()
+
+		if f != nil {
+			for key, values := range f.Header {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(f.StatusCode)
+			_, _ = w.Write([]byte(f.Body))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+ApiKey {
+			writeJSON(w, http.StatusUnauthorized, client.ApiError{Message: "Unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+		s.mu.Lock()
+		var hooks []func()
+		for _, h := range s.hooks {
+			if h.method != r.Method || !strings.HasPrefix(r.URL.Path, h.path) || (h.times > 0 && h.served >= h.times) {
+				continue
+			}
+
+			h.served++
+			hooks = append(hooks, h.run)
+		}
+		s.mu.Unlock()
+
+		for _, run := range hooks {
+			run()
+		}
+	})
+}
+
+func (s *Server) matchFault(r *http.Request) *fault {
+	for _, f := range s.faults {
+		if f.method != r.Method || !strings.HasPrefix(r.URL.Path, f.path) {
+			continue
+		}
+
+		if f.Times > 0 && f.served >= f.Times {
+			continue
+		}
+
+		f.served++
+		return f
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, client.ApiError{Message: "Not found"})
+}
+
+// paginate follows Workato, pages start at 1 and a page lower than 1 is the first page.
+func paginate[T any](r *http.Request, items []T) []T {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 100
+	}
+
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return make([]T, 0)
+	}
+
+	end := min(start+perPage, len(items))
+
+	return slices.Clone(items[start:end])
+}
+
+func pathId(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: fmt.Sprintf("invalid id %s", r.PathValue("id"))})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (s *Server) getWorkspace(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.workspace)
+}
+
+func (s *Server) getCollaborators(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.CommonPagination[client.Collaborator]{
+		Data:  paginate(r, s.collaborators),
+		Total: len(s.collaborators),
+	})
+}
+
+func (s *Server) getCollaboratorPrivileges(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	privileges, ok := s.privileges[id]
+	if !ok {
+		notFound(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, client.CommonPagination[*client.CollaboratorPrivilege]{
+		Data:  privileges,
+		Total: len(privileges),
+	})
+}
+
+func (s *Server) updateCollaboratorRoles(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		EnvRoles []envRole `json:"env_roles"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.collaborators, func(c client.Collaborator) bool {
+		return c.Id == id
+	})
+	if index < 0 {
+		notFound(w)
+		return
+	}
+
+	roles := make([]client.SimpleRole, 0, len(body.EnvRoles))
+	for _, envRole := range body.EnvRoles {
+		roles = append(roles, client.SimpleRole(envRole))
+	}
+
+	s.setCollaboratorRoles(index, roles)
+
+	writeJSON(w, http.StatusOK, s.collaborators[index])
+}
+
+// setCollaboratorRoles replaces the roles of a member and derives its privileges, s.mu must be held.
+func (s *Server) setCollaboratorRoles(index int, roles []client.SimpleRole) {
+	privileges := make([]*client.CollaboratorPrivilege, 0, len(roles))
+
+	for _, envRole := range roles {
+		privilege := &client.CollaboratorPrivilege{
+			EnvironmentType: envRole.EnvironmentType,
+			Name:            envRole.RoleName,
+			Privileges:      map[string][]string{},
+		}
+
+		for _, role := range s.roles {
+			if role.Name == envRole.RoleName {
+				privilege.Privileges = role.Privileges
+				privilege.FolderIDs = role.FolderIDs
+			}
+		}
+
+		privileges = append(privileges, privilege)
+	}
+
+	s.collaborators[index].Roles = roles
+	s.privileges[s.collaborators[index].Id] = privileges
+}
+
+func (s *Server) deleteCollaborator(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.collaborators, func(c client.Collaborator) bool {
+		return c.Id == id
+	})
+	if index < 0 {
+		notFound(w)
+		return
+	}
+
+	s.collaborators = slices.Delete(s.collaborators, index, index+1)
+	delete(s.privileges, id)
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+type envRole struct {
+	EnvironmentType string `json:"environment_type"`
+	RoleName        string `json:"name"`
+}
+
+func (s *Server) getInvitations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, client.CommonPagination[client.MemberInvitation]{
+		Data:  paginate(r, s.invitations),
+		Total: len(s.invitations),
+	})
+}
+
+func (s *Server) inviteCollaborator(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string    `json:"name"`
+		Email    string    `json:"email"`
+		EnvRoles []envRole `json:"env_roles"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: err.Error()})
+		return
+	}
+
+	if body.Email == "" || len(body.EnvRoles) == 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, client.ApiError{Message: "email and env_roles are required"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := slices.ContainsFunc(s.collaborators, func(c client.Collaborator) bool {
+		return strings.EqualFold(c.Email, body.Email)
+	}) || slices.ContainsFunc(s.invitations, func(i client.MemberInvitation) bool {
+		return strings.EqualFold(i.Email, body.Email)
+	})
+	if taken {
+		writeJSON(w, http.StatusUnprocessableEntity, client.ApiError{Message: "Email has already been taken"})
+		return
+	}
+
+	invitation := client.MemberInvitation{
+		Id:    len(s.invitations) + 1,
+		Name:  body.Name,
+		Email: body.Email,
+	}
+	for _, role := range body.EnvRoles {
+		invitation.Roles = append(invitation.Roles, client.SimpleRole(role))
+	}
+
+	s.invitations = append(s.invitations, invitation)
+
+	writeJSON(w, http.StatusOK, invitation)
+}
+
+func (s *Server) getRoles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.roles))
+}
+
+func (s *Server) createRole(w http.ResponseWriter, r *http.Request) {
+	var body client.RoleRequest
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.validRole(w, 0, body) {
+		return
+	}
+
+	id := 1
+	for _, role := range s.roles {
+		id = max(id, role.Id+1)
+	}
+
+	now := time.Now().UTC()
+	role := client.Role{
+		Id:          id,
+		Name:        body.Name,
+		Inheritable: body.Inheritable,
+		FolderIDs:   body.FolderIDs,
+		Privileges:  body.Privileges,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.roles = append(s.roles, role)
+
+	writeJSON(w, http.StatusOK, role)
+}
+
+func (s *Server) updateRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	var body client.RoleRequest
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.roles, func(role client.Role) bool {
+		return role.Id == id
+	})
+	if index < 0 {
+		notFound(w)
+		return
+	}
+
+	if !s.validRole(w, id, body) {
+		return
+	}
+
+	role := &s.roles[index]
+	role.Name = body.Name
+	role.Inheritable = body.Inheritable
+	role.FolderIDs = body.FolderIDs
+	role.Privileges = body.Privileges
+	role.UpdatedAt = time.Now().UTC()
+
+	writeJSON(w, http.StatusOK, role)
+}
+
+// validRole rejects empty and duplicated names like Workato, the caller holds the lock.
+func (s *Server) validRole(w http.ResponseWriter, id int, body client.RoleRequest) bool {
+	if body.Name == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, client.ApiError{Message: "Name can't be blank"})
+		return false
+	}
+
+	taken := slices.ContainsFunc(s.roles, func(role client.Role) bool {
+		return role.Id != id && role.Name == body.Name
+	})
+	if taken {
+		writeJSON(w, http.StatusUnprocessableEntity, client.ApiError{Message: "Name has already been taken"})
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) deleteRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.roles, func(role client.Role) bool {
+		return role.Id == id
+	})
+	if index < 0 {
+		notFound(w)
+		return
+	}
+
+	s.roles = slices.Delete(s.roles, index, index+1)
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+func (s *Server) getFolders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Without parent_id Workato returns the top level folders.
+	parentId := 0
+	if value := r.URL.Query().Get("parent_id"); value != "" {
+		var err error
+		parentId, err = strconv.Atoi(value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, client.ApiError{Message: "invalid parent_id"})
+			return
+		}
+	}
+
+	folders := make([]client.Folder, 0)
+	for _, folder := range s.folders {
+		if folder.ParentId == parentId {
+			folders = append(folders, folder)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, paginate(r, folders))
+}
+
+func (s *Server) getProjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.projects))
+}
+
+func (s *Server) getRecipes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folderId, err := strconv.Atoi(r.URL.Query().Get("folder_id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: "invalid folder_id"})
+		return
+	}
+
+	recipes := make([]client.Recipe, 0)
+	for _, recipe := range s.recipes {
+		if recipe.FolderId == folderId {
+			recipes = append(recipes, recipe)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, client.ItemsPagination[client.Recipe]{
+		Items: paginate(r, recipes),
+	})
+}
+
+func (s *Server) getConnections(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folderId, err := strconv.Atoi(r.URL.Query().Get("folder_id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: "invalid folder_id"})
+		return
+	}
+
+	connections := make([]client.Connection, 0)
+	for _, connection := range s.connections {
+		if connection.FolderId == folderId {
+			connections = append(connections, connection)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, connections)
+}
+
+func (s *Server) getApiCollections(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.collections))
+}
+
+func (s *Server) getApiClients(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.apiClients))
+}
+
+func (s *Server) getApiAccessProfiles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.profiles))
+}
+
+func (s *Server) updateApiAccessProfile(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	var body client.ApiAccessProfileRequest
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, client.ApiError{Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := slices.IndexFunc(s.profiles, func(p client.ApiAccessProfile) bool {
+		return p.Id == id
+	})
+	if index < 0 {
+		notFound(w)
+		return
+	}
+
+	if len(body.ApiCollectionIds) == 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, client.ApiError{Message: "Api collections can't be blank"})
+		return
+	}
+
+	profile := &s.profiles[index]
+	profile.Name = body.Name
+	profile.ApiClientId = body.ApiClientId
+	profile.ApiCollectionIds = body.ApiCollectionIds
+	profile.UpdatedAt = time.Now().UTC()
+
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (s *Server) setApiAccessProfileActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathId(w, r)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		index := slices.IndexFunc(s.profiles, func(p client.ApiAccessProfile) bool {
+			return p.Id == id
+		})
+		if index < 0 {
+			notFound(w)
+			return
+		}
+
+		s.profiles[index].Active = active
+
+		writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+	}
+}
+
+func (s *Server) getOnPremGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.onPremGroups))
+}
+
+func (s *Server) getOnPremAgents(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathId(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.onPremGroups, func(g client.OnPremGroup) bool {
+		return g.Id == id
+	}) {
+		notFound(w)
+		return
+	}
+
+	agents := make([]client.OnPremAgent, 0)
+	for _, agent := range s.onPremAgents {
+		if agent.OnPremGroupId == id {
+			agents = append(agents, agent)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, paginate(r, agents))
+}
+
+func (s *Server) getLookupTables(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, paginate(r, s.lookupTables))
+}
+
+// getDataTables wraps the tables in data without a total, like Workato.
+func (s *Server) getDataTables(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string][]client.DataTable{"data": paginate(r, s.dataTables)})
+}